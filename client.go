@@ -0,0 +1,181 @@
+package routeredis
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Client 持有独立的连接池, 路由表, 编码, 命令回调, 脚本, key模板和连接池回收超时, 多个Client之间互不影响, 用于同一进程内隔离多套配置(例如并行测试).
+// 包级函数全部作用于DefaultClient, 通过Client.NewKey创建的key执行命令时使用对应Client的路由和连接
+type Client struct {
+	redisPools        sync.Map
+	connSwapListeners sync.Map
+	drainingPools     sync.Map
+
+	keyRoutes         sync.Map
+	connKeyPrefixes   sync.Map
+	connRetryPolicies sync.Map
+	keyRouteRuleMu    sync.RWMutex
+	keyRoutePatterns  []*keyRoute // 按匹配顺序排列
+	keyRouteSeq       int64
+	fallbackKeyRoute  *keyRoute
+	resolvedKeyRoutes sync.Map // 通配规则和兜底连接的解析缓存, 规则变化时清空
+
+	defaultCodec atomic.Value
+	connCodecs   sync.Map
+	routeCodecs  sync.Map
+
+	onCmdDone           atomic.Value
+	middlewareMu        sync.Mutex
+	middlewares         atomic.Value // []CmdMiddleware
+	routeMiddlewares    sync.Map
+	pipelineMiddlewares atomic.Value // []PipelineMiddleware
+
+	topologyMu        sync.Mutex
+	currentTopology   *Topology
+	topologyListeners sync.Map
+
+	scripts          sync.Map
+	keyTemplates     sync.Map
+	poolDrainTimeout atomic.Int64
+}
+
+var defaultClient = NewClient()
+
+func NewClient() *Client {
+	c := &Client{}
+	c.defaultCodec.Store(codecHolder{codec: JSONCodec})
+	c.onCmdDone.Store(onCmdDoneHolder{})
+	c.poolDrainTimeout.Store(int64(DefaultPoolDrainTimeout))
+	return c
+}
+
+// DefaultClient 包级函数使用的Client
+func DefaultClient() *Client {
+	return defaultClient
+}
+
+// NewKey 创建属于c的key, 命令按c的路由和连接执行
+func (c *Client) NewKey(route string, tmpl string, args ...any) *Key {
+	key := NewKey(route, tmpl, args...)
+	key.c = c
+	return key
+}
+
+type onCmdDoneHolder struct {
+	fn OnCmdDoneFunc
+}
+
+// SetOnCmdDone 设置c的命令回调, DefaultClient未设置时使用包级变量OnCmdDone
+func (c *Client) SetOnCmdDone(fn OnCmdDoneFunc) {
+	c.onCmdDone.Store(onCmdDoneHolder{fn: fn})
+}
+
+func (c *Client) cmdDoneHook() OnCmdDoneFunc {
+	if fn := c.onCmdDone.Load().(onCmdDoneHolder).fn; fn != nil {
+		return fn
+	}
+	if c == defaultClient {
+		return OnCmdDone
+	}
+	return nil
+}
+
+// Disconnect 注销连接, 同时移除连接的key前缀, 编码和重试策略, 连接池在借出的连接全部归还或者超过PoolDrainTimeout后关闭,
+// 仍指向该连接的路由会返回ErrRedisConnPoolNotRegistered
+func (c *Client) Disconnect(connName string) error {
+	pool, ok := c.redisPools.LoadAndDelete(connName)
+	if !ok {
+		return ErrRedisConnPoolNotRegistered
+	}
+	c.connKeyPrefixes.Delete(connName)
+	c.connCodecs.Delete(connName)
+	c.connRetryPolicies.Delete(connName)
+	c.drainPool(pool.(RedisPool))
+	return nil
+}
+
+// UnregisterKeyRoute 注销精确路由, 分片路由或者通配规则, 同时移除路由的编码
+func (c *Client) UnregisterKeyRoute(route string) error {
+	c.routeCodecs.Delete(route)
+
+	if _, ok := c.keyRoutes.LoadAndDelete(route); ok {
+		c.resetResolvedKeyRoutes()
+		return nil
+	}
+
+	if c.removeKeyRoutePattern(route) {
+		return nil
+	}
+
+	return ErrRedisKeyRouteNotRegistered
+}
+
+// CloseAll 注销全部连接, 等待借出的连接归还后关闭连接池, 包括之前被替换或者注销后仍在等待归还的连接池,
+// ctx结束时强制关闭剩余的连接池并返回ctx.Err(). 路由保持注册, 重新Connect后可继续使用
+func (c *Client) CloseAll(ctx context.Context) error {
+	c.redisPools.Range(func(connName, pool any) bool {
+		if _, ok := c.redisPools.LoadAndDelete(connName); ok {
+			c.drainPool(pool.(RedisPool))
+		}
+		return true
+	})
+
+	var err error
+	c.drainingPools.Range(func(key, _ any) bool {
+		d := key.(*drainingPool)
+		select {
+		case <-d.done:
+		case <-ctx.Done():
+			d.cancel()
+			<-d.done
+			err = ctx.Err()
+		}
+		return true
+	})
+
+	return err
+}
+
+// ConnNames 已注册的连接名, 按字典序排列
+func (c *Client) ConnNames() []string {
+	return sortedSyncMapKeys(&c.redisPools)
+}
+
+// KeyRoutes 已注册的精确路由和分片路由, 按字典序排列, 通配规则通过KeyRoutePatterns获取
+func (c *Client) KeyRoutes() []string {
+	return sortedSyncMapKeys(&c.keyRoutes)
+}
+
+func sortedSyncMapKeys(m *sync.Map) []string {
+	var keys []string
+	m.Range(func(k, _ any) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+func Disconnect(connName string) error {
+	return defaultClient.Disconnect(connName)
+}
+
+func UnregisterKeyRoute(route string) error {
+	return defaultClient.UnregisterKeyRoute(route)
+}
+
+// CloseAll 关闭DefaultClient的全部连接池, 通常在进程退出前调用
+func CloseAll(ctx context.Context) error {
+	return defaultClient.CloseAll(ctx)
+}
+
+func ConnNames() []string {
+	return defaultClient.ConnNames()
+}
+
+func KeyRoutes() []string {
+	return defaultClient.KeyRoutes()
+}
@@ -0,0 +1,161 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientIsolation(t *testing.T) {
+	newServer := func() (string, func() []string) {
+		var (
+			mu   sync.Mutex
+			keys []string
+		)
+		addr := newFakeRedis(t, func(args []string) any {
+			mu.Lock()
+			defer mu.Unlock()
+			keys = append(keys, args[1])
+			return "OK"
+		})
+		return addr, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return slices.Clone(keys)
+		}
+	}
+	addr1, seen1 := newServer()
+	addr2, seen2 := newServer()
+
+	c1, c2 := NewClient(), NewClient()
+	for _, item := range []struct {
+		c      *Client
+		addr   string
+		prefix string
+	}{{c1, addr1, "c1:"}, {c2, addr2, "c2:"}} {
+		if err := item.c.ConnectByConf("main", &ConnConf{Servers: []string{item.addr}, KeyPrefix: item.prefix}); err != nil {
+			t.Fatal(err)
+		}
+		item.c.RegisterKeyRoute("user", "main")
+	}
+
+	var hooked []string
+	c1.SetOnCmdDone(func(_ string, _ *TTL, _ time.Duration, cmd string, key *Key, _ error, _ ...any) {
+		hooked = append(hooked, key.RedisKey())
+	})
+
+	if err := Set(c1.NewKey("user", "1"), "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(NewKey("user", "2").WithClient(c2), "v", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := seen1(); !slices.Equal(keys, []string{"c1:1"}) {
+		t.Fatalf("unexpected keys on c1 %v", keys)
+	}
+	if keys := seen2(); !slices.Equal(keys, []string{"c2:2"}) {
+		t.Fatalf("unexpected keys on c2 %v", keys)
+	}
+	if !slices.Equal(hooked, []string{"c1:1"}) {
+		t.Fatalf("expect hook only on c1, got %v", hooked)
+	}
+
+	if _, err := RouteConnName("user"); !errors.Is(err, ErrRedisKeyRouteNotRegistered) {
+		t.Fatalf("expect default client untouched, got %v", err)
+	}
+
+	// 脚本, key模板和连接池回收超时同样按Client隔离
+	c1.RegisterScript("isolation", "return 1")
+	if _, err := c2.GetScript("isolation"); !errors.Is(err, ErrScriptNotRegistered) {
+		t.Fatalf("expect script only on c1, got %v", err)
+	}
+
+	tmpl := c1.MustRegisterKeyTemplate(&KeyTemplateConf{Route: "user", Pattern: "tmpl:{uid}"})
+	if _, err := c2.RegisterKeyTemplate(&KeyTemplateConf{Route: "user", Pattern: "tmpl:{uid}"}); err != nil {
+		t.Fatalf("expect template registrable on c2, got %v", err)
+	}
+	if slices.Contains(KeyTemplates(), tmpl) {
+		t.Fatal("expect template only on c1")
+	}
+	if err := Set(tmpl.MustKey(3), "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	if keys := seen1(); !slices.Equal(keys, []string{"c1:1", "c1:tmpl:3"}) {
+		t.Fatalf("expect template key on c1, got %v", keys)
+	}
+
+	c1.SetPoolDrainTimeout(time.Second)
+	if c2.PoolDrainTimeout() != DefaultPoolDrainTimeout || PoolDrainTimeout() != DefaultPoolDrainTimeout {
+		t.Fatalf("expect drain timeout only on c1, got %v %v", c2.PoolDrainTimeout(), PoolDrainTimeout())
+	}
+}
+
+func TestClientLifecycle(t *testing.T) {
+	addr := newFakeRedis(t, func(args []string) any {
+		return "OK"
+	})
+
+	c := NewClient()
+	for _, connName := range []string{"a", "b"} {
+		if err := c.ConnectByConf(connName, &ConnConf{Servers: []string{addr}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.RegisterKeyRoute("order", "a")
+	c.RegisterShardedKeyRoute("user", "a", "b")
+	if err := c.RegisterKeyRoutePattern("log.*", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if names := c.ConnNames(); !slices.Equal(names, []string{"a", "b"}) {
+		t.Fatalf("unexpected conns %v", names)
+	}
+	if routes := c.KeyRoutes(); !slices.Equal(routes, []string{"order", "user"}) {
+		t.Fatalf("unexpected routes %v", routes)
+	}
+
+	if err := c.UnregisterKeyRoute("log.*"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RouteConnName("log.1"); !errors.Is(err, ErrRedisKeyRouteNotRegistered) {
+		t.Fatalf("expect pattern removed, got %v", err)
+	}
+	if err := c.UnregisterKeyRoute("log.*"); !errors.Is(err, ErrRedisKeyRouteNotRegistered) {
+		t.Fatalf("expect not registered, got %v", err)
+	}
+
+	if err := c.Disconnect("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetConnPool("b"); !errors.Is(err, ErrRedisConnPoolNotRegistered) {
+		t.Fatalf("expect conn removed, got %v", err)
+	}
+
+	pool, _ := c.GetConnPool("a")
+	conn := pool.Get()
+	if _, err := conn.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 借出的连接未归还, CloseAll在ctx超时后强制关闭
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := c.CloseAll(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+	_ = conn.Close()
+
+	if names := c.ConnNames(); len(names) != 0 {
+		t.Fatalf("expect no conns after close, got %v", names)
+	}
+	if _, err := pool.Get().Do("PING"); err == nil {
+		t.Fatal("expect pool closed")
+	}
+	if err := c.CloseAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
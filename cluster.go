@@ -0,0 +1,114 @@
+package routeredis
+
+import (
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+var ErrClusterSlotNotCovered = errors.New("redis cluster slot not covered")
+
+type clusterSlotNode struct {
+	start int
+	end   int
+	addr  string
+}
+
+// SlotAddr 返回槽位所在主节点的地址, 槽位分布在首次使用时通过CLUSTER SLOTS加载并缓存,
+// 遇到MOVED等重定向时应调用ResetSlots重新加载
+func (r *RedisCluster) SlotAddr(slot int) (string, error) {
+	r.slotMu.RLock()
+	nodes := r.slotNodes
+	r.slotMu.RUnlock()
+
+	if nodes == nil {
+		var err error
+		if nodes, err = r.loadSlotNodes(); err != nil {
+			return "", err
+		}
+	}
+
+	for _, node := range nodes {
+		if slot >= node.start && slot <= node.end {
+			return node.addr, nil
+		}
+	}
+
+	return "", ErrClusterSlotNotCovered
+}
+
+func (r *RedisCluster) KeyAddr(key string) (string, error) {
+	return r.SlotAddr(redisc.Slot(key))
+}
+
+func (r *RedisCluster) ResetSlots() {
+	r.slotMu.Lock()
+	r.slotNodes = nil
+	r.slotMu.Unlock()
+}
+
+func (r *RedisCluster) loadSlotNodes() ([]clusterSlotNode, error) {
+	conn := r.Cluster.Get()
+	defer conn.Close()
+
+	res, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]clusterSlotNode, 0, len(res))
+	for _, item := range res {
+		slotRange, err := redis.Values(item, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(slotRange) < 3 {
+			continue
+		}
+
+		start, err := redis.Int(slotRange[0], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		end, err := redis.Int(slotRange[1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		master, err := redis.Values(slotRange[2], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(master) < 2 {
+			continue
+		}
+
+		host, err := redis.String(master[0], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		port, err := redis.Int(master[1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, clusterSlotNode{
+			start: start,
+			end:   end,
+			addr:  net.JoinHostPort(host, strconv.Itoa(port)),
+		})
+	}
+
+	r.slotMu.Lock()
+	r.slotNodes = nodes
+	r.slotMu.Unlock()
+
+	return nodes, nil
+}
@@ -0,0 +1,104 @@
+package routeredis
+
+import "strings"
+
+var readOnlyCmds = map[string]struct{}{
+	"GET":              {},
+	"MGET":             {},
+	"STRLEN":           {},
+	"GETRANGE":         {},
+	"EXISTS":           {},
+	"TYPE":             {},
+	"TTL":              {},
+	"PTTL":             {},
+	"HGET":             {},
+	"HMGET":            {},
+	"HGETALL":          {},
+	"HKEYS":            {},
+	"HVALS":            {},
+	"HLEN":             {},
+	"HEXISTS":          {},
+	"HSCAN":            {},
+	"LLEN":             {},
+	"LRANGE":           {},
+	"LINDEX":           {},
+	"SCARD":            {},
+	"SISMEMBER":        {},
+	"SMEMBERS":         {},
+	"SRANDMEMBER":      {},
+	"SSCAN":            {},
+	"ZCARD":            {},
+	"ZCOUNT":           {},
+	"ZSCORE":           {},
+	"ZRANK":            {},
+	"ZREVRANK":         {},
+	"ZRANGE":           {},
+	"ZREVRANGE":        {},
+	"ZRANGEBYSCORE":    {},
+	"ZREVRANGEBYSCORE": {},
+	"ZSCAN":            {},
+	"XLEN":             {},
+	"XRANGE":           {},
+	"XREVRANGE":        {},
+	"SCAN":             {},
+}
+
+// IsReadOnlyCmd 命令是否只读, 只读命令可以发往从库执行
+func IsReadOnlyCmd(cmd string) bool {
+	_, ok := readOnlyCmds[strings.ToUpper(cmd)]
+	return ok
+}
+
+// idempotentWriteCmds 重复执行结果不变的写命令, 未列出的写命令(INCRBY, LPUSH等)视为非幂等
+var idempotentWriteCmds = map[string]struct{}{
+	"SET":       {},
+	"SETEX":     {},
+	"PSETEX":    {},
+	"MSET":      {},
+	"DEL":       {},
+	"UNLINK":    {},
+	"EXPIRE":    {},
+	"PEXPIRE":   {},
+	"EXPIREAT":  {},
+	"PEXPIREAT": {},
+	"PERSIST":   {},
+	"HSET":      {},
+	"HMSET":     {},
+	"HDEL":      {},
+	"SADD":      {},
+	"SREM":      {},
+	"ZREM":      {},
+	"PFADD":     {},
+}
+
+// conditionalSetOpts 带这些选项的SET重复执行时应答与首次不同(NX/XX返回nil, GET返回新值), 视为非幂等
+var conditionalSetOpts = map[string]struct{}{
+	"NX":  {},
+	"XX":  {},
+	"GET": {},
+}
+
+// IsIdempotentCmd 命令是否可以安全地重复执行, 只读命令均为幂等, args为key之后的参数, 用于识别SET NX等条件写
+func IsIdempotentCmd(cmd string, args ...any) bool {
+	cmd = strings.ToUpper(cmd)
+	if _, ok := readOnlyCmds[cmd]; ok {
+		return true
+	}
+	if _, ok := idempotentWriteCmds[cmd]; !ok {
+		return false
+	}
+	if cmd != "SET" || len(args) < 2 {
+		return true
+	}
+	// 跳过value, 只检查选项
+	for _, arg := range args[1:] {
+		opt, ok := arg.(string)
+		if !ok {
+			continue
+		}
+		if _, ok = conditionalSetOpts[strings.ToUpper(opt)]; ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package routeredis
+
+import (
+	"errors"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrCodecNotProtoMessage = errors.New("redis codec value is not proto.Message")
+	ErrCodecNotRawBytes     = errors.New("redis codec value is not []byte or string")
+)
+
+// Codec 写入命令对非字符串的值编码, GetObj和Message.Decode解码, 字符串值总是原样写入
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	JSONCodec     Codec = jsonCodec{}
+	MsgpackCodec  Codec = msgpackCodec{}
+	ProtobufCodec Codec = protobufCodec{}
+	RawCodec      Codec = rawCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return jsoniter.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return jsoniter.Unmarshal(data, v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrCodecNotProtoMessage
+	}
+	return proto.Marshal(msg)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return ErrCodecNotProtoMessage
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// rawCodec 只接受[]byte和string, 原样写入和读出
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	switch data := v.(type) {
+	case []byte:
+		return data, nil
+	case string:
+		return []byte(data), nil
+	}
+	return nil, ErrCodecNotRawBytes
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	switch dst := v.(type) {
+	case *[]byte:
+		*dst = append((*dst)[:0], data...)
+		return nil
+	case *string:
+		*dst = string(data)
+		return nil
+	}
+	return ErrCodecNotRawBytes
+}
+
+type codecHolder struct {
+	codec Codec
+}
+
+func SetDefaultCodec(codec Codec) {
+	defaultClient.SetDefaultCodec(codec)
+}
+
+func DefaultCodec() Codec {
+	return defaultClient.DefaultCodec()
+}
+
+func SetConnCodec(connName string, codec Codec) {
+	defaultClient.SetConnCodec(connName, codec)
+}
+
+func SetRouteCodec(route string, codec Codec) {
+	defaultClient.SetRouteCodec(route, codec)
+}
+
+func RouteCodec(route string) Codec {
+	return defaultClient.RouteCodec(route)
+}
+
+// SetDefaultCodec 未按路由和连接名指定编码时使用, 默认为JSONCodec
+func (c *Client) SetDefaultCodec(codec Codec) {
+	c.defaultCodec.Store(codecHolder{codec: codec})
+}
+
+func (c *Client) DefaultCodec() Codec {
+	return c.defaultCodec.Load().(codecHolder).codec
+}
+
+func (c *Client) SetConnCodec(connName string, codec Codec) {
+	c.connCodecs.Store(connName, codec)
+}
+
+func (c *Client) SetRouteCodec(route string, codec Codec) {
+	c.routeCodecs.Store(route, codec)
+}
+
+// RouteCodec 按路由, 路由对应的连接名, Client默认的顺序查找编码
+func (c *Client) RouteCodec(route string) Codec {
+	if codec, ok := c.routeCodecs.Load(route); ok {
+		return codec.(Codec)
+	}
+
+	if connName, err := c.RouteConnName(route); err == nil {
+		if codec, ok := c.connCodecs.Load(connName); ok {
+			return codec.(Codec)
+		}
+	}
+
+	return c.DefaultCodec()
+}
+
+func encodeValue(key *Key, data any) (string, error) {
+	if str, ok := data.(string); ok {
+		return str, nil
+	}
+
+	buf, err := key.Client().RouteCodec(key.Route).Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+func decodeValue(key *Key, data []byte, v any) error {
+	return key.Client().RouteCodec(key.Route).Unmarshal(data, v)
+}
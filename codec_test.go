@@ -0,0 +1,186 @@
+package routeredis
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestRouteCodec(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		store = map[string]string{}
+	)
+	addr := newFakeRedis(t, func(args []string) any {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch args[0] {
+		case "SET":
+			store[args[1]] = args[2]
+			return "OK"
+		case "GET":
+			val, ok := store[args[1]]
+			if !ok {
+				return nil
+			}
+			return val
+		}
+		return nil
+	})
+
+	const connName = "codec"
+	ConnectByConf(connName, &ConnConf{Servers: []string{addr}})
+	RegisterKeyRoute("codec.json", connName)
+	RegisterKeyRoute("codec.msgpack", connName)
+	RegisterKeyRoute("codec.raw", connName)
+	SetConnCodec(connName, MsgpackCodec)
+	SetRouteCodec("codec.raw", RawCodec)
+
+	if RouteCodec("codec.json") != MsgpackCodec || RouteCodec("codec.raw") != RawCodec || RouteCodec("codec.none") != DefaultCodec() {
+		t.Fatal("unexpected codec resolution")
+	}
+
+	type user struct {
+		Id   int
+		Name string
+	}
+	key := NewKey("codec.msgpack", "user:%d", 1)
+	if err := Set(key, &user{Id: 1, Name: "foo"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored user
+	if err := msgpack.Unmarshal([]byte(store[key.Key]), &stored); err != nil || stored.Name != "foo" {
+		t.Fatalf("expect msgpack payload, got %q %v", store[key.Key], err)
+	}
+
+	var got user
+	if ok, err := GetObj(key, &got); err != nil || !ok || got != stored {
+		t.Fatalf("expect decoded user, got %+v %v %v", got, ok, err)
+	}
+
+	rawKey := NewKey("codec.raw", "blob")
+	if err := Set(rawKey, []byte{0, 1, 2}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var blob []byte
+	if ok, err := GetObj(rawKey, &blob); err != nil || !ok || string(blob) != "\x00\x01\x02" {
+		t.Fatalf("expect raw bytes, got %v %v %v", blob, ok, err)
+	}
+}
+
+func TestCodecSetMembers(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		set = map[string]bool{}
+	)
+	addr := newFakeRedis(t, func(args []string) any {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch args[0] {
+		case "SADD":
+			set[args[2]] = true
+			return 1
+		case "SISMEMBER":
+			if set[args[2]] {
+				return 1
+			}
+			return 0
+		case "SREM":
+			if !set[args[2]] {
+				return 0
+			}
+			delete(set, args[2])
+			return 1
+		}
+		return 1
+	})
+
+	c := NewClient()
+	if err := c.ConnectByConf("main", &ConnConf{Servers: []string{addr}}); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("codec.set", "main")
+	c.SetRouteCodec("codec.set", MsgpackCodec)
+	key := c.NewKey("codec.set", "k")
+
+	type member struct {
+		Id int
+	}
+	if err := AsyncSadd(key, &member{Id: 1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Sismember(key, &member{Id: 1}); err != nil || !ok {
+		t.Fatalf("expect encoded member found, got %v %v", ok, err)
+	}
+	if ok, err := Srem(key, &member{Id: 1}); err != nil || !ok {
+		t.Fatalf("expect encoded member removed, got %v %v", ok, err)
+	}
+	if ok, err := Sismember(key, &member{Id: 1}); err != nil || ok {
+		t.Fatalf("expect member gone after SREM, got %v %v", ok, err)
+	}
+}
+
+func TestCodecMembers(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		members = map[string]string{}
+	)
+	addr := newFakeRedis(t, func(args []string) any {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch args[0] {
+		case "ZADD":
+			members[args[0]] = args[3]
+			return 1
+		case "SET", "SETNX", "ZSCORE":
+			members[args[0]] = args[2]
+			return "1"
+		case "ZINCRBY":
+			members[args[0]] = args[3]
+			return "2"
+		}
+		return 1
+	})
+
+	c := NewClient()
+	if err := c.ConnectByConf("main", &ConnConf{Servers: []string{addr}}); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("codec.member", "main")
+	c.SetRouteCodec("codec.member", MsgpackCodec)
+	key := c.NewKey("codec.member", "k")
+
+	type member struct {
+		Id int
+	}
+	m := &member{Id: 1}
+	if err := Zadd(key, 1, m, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Zscore(key, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := Zincrby(key, 1, m, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Setnx(key, m, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Setnx(key, m, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, cmd := range []string{"ZSCORE", "ZINCRBY", "SET", "SETNX"} {
+		if members[cmd] != members["ZADD"] {
+			t.Fatalf("expect %s to use the encoded member %q, got %q", cmd, members["ZADD"], members[cmd])
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package routeredis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrTopologyFormatNotSupported = errors.New("redis topology format not supported")
+
+// Topology 连接和路由的完整声明, 字段名与json标签一致, yaml和toml使用相同的字段名,
+// 其中连接配置按connConfDoc的snake_case字段名编解码, 单独编解码ConnConf时仍使用Go字段名
+type Topology struct {
+	Conns map[string]*ConnConf `json:"conns"`
+	// Routes 路由名包含*?[时作为通配规则注册
+	Routes     map[string]*RouteConf `json:"routes"`
+	Fallback   *RouteConf            `json:"fallback"`    // 未注册路由的兜底连接
+	DefaultTTL int64                 `json:"default_ttl"` // 路由未配置ttl时使用
+}
+
+type RouteConf struct {
+	Conn         string   `json:"conn"`
+	Shards       []string `json:"shards"` // 配置后为分片路由, 与Conn互斥
+	VirtualNodes int      `json:"virtual_nodes"`
+	Replicas     []string `json:"replicas"`
+	Prefix       string   `json:"prefix"`
+	TTL          int64    `json:"ttl"`
+}
+
+// topologyDoc Topology的文件格式
+type topologyDoc struct {
+	Conns      map[string]*connConfDoc `json:"conns"`
+	Routes     map[string]*RouteConf   `json:"routes"`
+	Fallback   *RouteConf              `json:"fallback"`
+	DefaultTTL int64                   `json:"default_ttl"`
+}
+
+// connConfDoc ConnConf在拓扑文件中的格式, ConnConf增加字段时需要同步
+type connConfDoc struct {
+	IdleCount              int             `json:"idle_count"`
+	IdleTimeoutMillSec     int             `json:"idle_timeout_mill_sec"`
+	MaxConnLifetimeMillSec int             `json:"max_conn_lifetime_mill_sec"`
+	MaxConnPoolSize        int             `json:"max_conn_pool_size"`
+	Servers                []string        `json:"servers"`
+	Username               string          `json:"username"`
+	Password               string          `json:"password"`
+	DB                     int             `json:"db"`
+	TLS                    *tlsConfDoc     `json:"tls"`
+	EnabledCluster         bool            `json:"enabled_cluster"`
+	SentinelAddrs          []string        `json:"sentinel_addrs"`
+	SentinelMasterName     string          `json:"sentinel_master_name"`
+	SentinelPassword       string          `json:"sentinel_password"`
+	ReadFromReplicas       bool            `json:"read_from_replicas"`
+	KeyPrefix              string          `json:"key_prefix"`
+	Retry                  *retryPolicyDoc `json:"retry"`
+}
+
+// tlsConfDoc与retryPolicyDoc只有标签与原类型不同, 可以直接转换
+type tlsConfDoc struct {
+	CAFile             string `json:"ca_file"`
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	ServerName         string `json:"server_name"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+type retryPolicyDoc struct {
+	MaxAttempts         int      `json:"max_attempts"`
+	MinBackoffMillSec   int      `json:"min_backoff_mill_sec"`
+	MaxBackoffMillSec   int      `json:"max_backoff_mill_sec"`
+	Jitter              float64  `json:"jitter"`
+	RetryableErrors     []string `json:"retryable_errors"`
+	RetryNonIdempotent  bool     `json:"retry_non_idempotent"`
+	ClusterMaxRedirects int      `json:"cluster_max_redirects"`
+}
+
+func (d *connConfDoc) connConf() *ConnConf {
+	if d == nil {
+		return nil
+	}
+	return &ConnConf{
+		IdleCount:              d.IdleCount,
+		IdleTimeoutMillSec:     d.IdleTimeoutMillSec,
+		MaxConnLifetimeMillSec: d.MaxConnLifetimeMillSec,
+		MaxConnPoolSize:        d.MaxConnPoolSize,
+		Servers:                d.Servers,
+		Username:               d.Username,
+		Password:               d.Password,
+		DB:                     d.DB,
+		TLS:                    (*TLSConf)(d.TLS),
+		EnabledCluster:         d.EnabledCluster,
+		SentinelAddrs:          d.SentinelAddrs,
+		SentinelMasterName:     d.SentinelMasterName,
+		SentinelPassword:       d.SentinelPassword,
+		ReadFromReplicas:       d.ReadFromReplicas,
+		KeyPrefix:              d.KeyPrefix,
+		Retry:                  (*RetryPolicy)(d.Retry),
+	}
+}
+
+func newConnConfDoc(conf *ConnConf) *connConfDoc {
+	if conf == nil {
+		return nil
+	}
+	return &connConfDoc{
+		IdleCount:              conf.IdleCount,
+		IdleTimeoutMillSec:     conf.IdleTimeoutMillSec,
+		MaxConnLifetimeMillSec: conf.MaxConnLifetimeMillSec,
+		MaxConnPoolSize:        conf.MaxConnPoolSize,
+		Servers:                conf.Servers,
+		Username:               conf.Username,
+		Password:               conf.Password,
+		DB:                     conf.DB,
+		TLS:                    (*tlsConfDoc)(conf.TLS),
+		EnabledCluster:         conf.EnabledCluster,
+		SentinelAddrs:          conf.SentinelAddrs,
+		SentinelMasterName:     conf.SentinelMasterName,
+		SentinelPassword:       conf.SentinelPassword,
+		ReadFromReplicas:       conf.ReadFromReplicas,
+		KeyPrefix:              conf.KeyPrefix,
+		Retry:                  (*retryPolicyDoc)(conf.Retry),
+	}
+}
+
+func (t *Topology) UnmarshalJSON(data []byte) error {
+	var doc topologyDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	*t = Topology{Routes: doc.Routes, Fallback: doc.Fallback, DefaultTTL: doc.DefaultTTL}
+	if doc.Conns != nil {
+		t.Conns = make(map[string]*ConnConf, len(doc.Conns))
+		for connName, conf := range doc.Conns {
+			t.Conns[connName] = conf.connConf()
+		}
+	}
+
+	return nil
+}
+
+func (t *Topology) MarshalJSON() ([]byte, error) {
+	doc := topologyDoc{Routes: t.Routes, Fallback: t.Fallback, DefaultTTL: t.DefaultTTL}
+	if t.Conns != nil {
+		doc.Conns = make(map[string]*connConfDoc, len(t.Conns))
+		for connName, conf := range t.Conns {
+			doc.Conns[connName] = newConnConfDoc(conf)
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// LoadTopologyFile 按扩展名(.json, .yaml, .yml, .toml)解析拓扑文件
+func LoadTopologyFile(file string) (*Topology, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return LoadTopology(data, strings.TrimPrefix(filepath.Ext(file), "."))
+}
+
+// LoadTopologyFromEnv 从环境变量读取拓扑, 变量内容为yaml或者json文档
+func LoadTopologyFromEnv(name string) (*Topology, error) {
+	data, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("redis topology env %s not set", name)
+	}
+	return LoadTopology([]byte(data), "yaml")
+}
+
+// LoadTopology format为json, yaml/yml或者toml, yaml和toml先转换为json再解析, 保证三种格式的字段名一致
+func LoadTopology(data []byte, format string) (*Topology, error) {
+	switch strings.ToLower(format) {
+	case "json":
+	case "yaml", "yml":
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	case "toml":
+		var doc map[string]any
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrTopologyFormatNotSupported, format)
+	}
+
+	topo := &Topology{}
+	if err := json.Unmarshal(data, topo); err != nil {
+		return nil, err
+	}
+
+	return topo, nil
+}
+
+// Validate 返回全部问题, 每条错误以出错条目的路径开头, 例如"routes.order.replicas[1]: ..."
+func (t *Topology) Validate() error {
+	var errs []error
+	addErr := func(entry string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", entry, fmt.Sprintf(format, args...)))
+	}
+
+	for _, connName := range sortedKeys(t.Conns) {
+		conf := t.Conns[connName]
+		entry := "conns." + connName
+		switch {
+		case conf == nil:
+			addErr(entry, "empty conn")
+		case len(conf.SentinelAddrs) > 0 && conf.EnabledCluster:
+			addErr(entry, "sentinel_addrs and enabled_cluster are exclusive")
+		case len(conf.SentinelAddrs) > 0:
+			if conf.SentinelMasterName == "" {
+				addErr(entry, "sentinel_master_name is required")
+			}
+		case len(conf.Servers) == 0:
+			addErr(entry, "servers is empty")
+		case conf.EnabledCluster && conf.DB != 0:
+			addErr(entry, "cluster only supports db 0")
+		case !conf.EnabledCluster && len(conf.Servers) > 1:
+			addErr(entry, "only one server is allowed without cluster")
+		}
+	}
+
+	checkConn := func(entry string, connName string) {
+		if _, ok := t.Conns[connName]; !ok {
+			addErr(entry, "conn %q not defined", connName)
+		}
+	}
+	checkRoute := func(entry string, conf *RouteConf, isFallback bool) {
+		if conf == nil {
+			addErr(entry, "empty route")
+			return
+		}
+
+		switch {
+		case conf.Conn != "" && len(conf.Shards) > 0:
+			addErr(entry, "conn and shards are exclusive")
+		case conf.Conn == "" && len(conf.Shards) == 0:
+			addErr(entry, "conn or shards is required")
+		case conf.Conn != "":
+			checkConn(entry+".conn", conf.Conn)
+		case isFallback:
+			addErr(entry, "fallback can not be sharded")
+		default:
+			for i, connName := range conf.Shards {
+				checkConn(fmt.Sprintf("%s.shards[%d]", entry, i), connName)
+			}
+			if len(conf.Replicas) > 0 {
+				addErr(entry, "sharded route does not support replicas")
+			}
+		}
+
+		for i, connName := range conf.Replicas {
+			checkConn(fmt.Sprintf("%s.replicas[%d]", entry, i), connName)
+		}
+	}
+
+	for _, route := range sortedKeys(t.Routes) {
+		entry := "routes." + route
+		if isKeyRoutePattern(route) {
+			if _, err := path.Match(route, ""); err != nil {
+				addErr(entry, "bad pattern: %v", err)
+			}
+			if conf := t.Routes[route]; conf != nil && len(conf.Shards) > 0 {
+				addErr(entry, "pattern route can not be sharded")
+			}
+		}
+		checkRoute(entry, t.Routes[route], false)
+	}
+
+	if t.Fallback != nil {
+		checkRoute("fallback", t.Fallback, true)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t *Topology) applyRoute(c *Client, route string, conf *RouteConf) error {
+	if len(conf.Shards) > 0 {
+		return c.RegisterShardedKeyRouteWithOpts(route, &ShardedKeyRouteOpts{
+			Prefix:       conf.Prefix,
+			VirtualNodes: conf.VirtualNodes,
+			TTL:          t.routeTTL(conf),
+		}, conf.Shards...)
+	}
+
+	if isKeyRoutePattern(route) {
+		return c.RegisterKeyRoutePatternWithOpts(route, conf.Conn, t.routeOpts(conf))
+	}
+
+	c.RegisterKeyRouteWithOpts(route, conf.Conn, t.routeOpts(conf))
+
+	return nil
+}
+
+func (t *Topology) routeOpts(conf *RouteConf) *KeyRouteOpts {
+	return &KeyRouteOpts{
+		Prefix:   conf.Prefix,
+		Replicas: conf.Replicas,
+		TTL:      t.routeTTL(conf),
+	}
+}
+
+func (t *Topology) routeTTL(conf *RouteConf) int64 {
+	if conf.TTL > 0 {
+		return conf.TTL
+	}
+	return t.DefaultTTL
+}
+
+func isKeyRoutePattern(route string) bool {
+	return strings.ContainsAny(route, `*?[\`)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,175 @@
+package routeredis
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadTopology(t *testing.T) {
+	addr := newFakeRedis(t, func(args []string) any {
+		return "OK"
+	})
+
+	yamlDoc := `
+default_ttl: 60
+conns:
+  cfg.main:
+    servers: ["` + addr + `"]
+    key_prefix: "test:"
+routes:
+  cfg.user:
+    conn: cfg.main
+    prefix: "user:"
+    ttl: 30
+  cfg.order.*:
+    conn: cfg.main
+`
+	tomlDoc := `
+default_ttl = 60
+
+[conns."cfg.main"]
+servers = ["` + addr + `"]
+key_prefix = "test:"
+
+[routes."cfg.user"]
+conn = "cfg.main"
+prefix = "user:"
+ttl = 30
+
+[routes."cfg.order.*"]
+conn = "cfg.main"
+`
+	for format, doc := range map[string]string{"yaml": yamlDoc, "toml": tomlDoc} {
+		topo, err := LoadTopology([]byte(doc), format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if topo.Conns["cfg.main"].KeyPrefix != "test:" || topo.Routes["cfg.user"].TTL != 30 || topo.DefaultTTL != 60 {
+			t.Fatalf("%s: unexpected topology %+v", format, topo)
+		}
+
+		if err = topo.Apply(); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+	}
+
+	if got := NewKey("cfg.user", "1").RedisKey(); got != "test:user:1" {
+		t.Fatalf("unexpected redis key %s", got)
+	}
+	if RouteTTL("cfg.user") != 30 || RouteTTL("cfg.order.1") != 60 {
+		t.Fatalf("unexpected route ttl %d %d", RouteTTL("cfg.user"), RouteTTL("cfg.order.1"))
+	}
+}
+
+func TestTopologyValidate(t *testing.T) {
+	topo, err := LoadTopology([]byte(`{
+		"conns": {"a": {"servers": []}, "b": {"servers": ["127.0.0.1:6379"]}},
+		"routes": {
+			"r1": {"conn": "missing"},
+			"r2": {"conn": "b", "replicas": ["b", "nope"]},
+			"r3": {"conn": "b", "shards": ["b"]}
+		}
+	}`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = topo.Validate()
+	if err == nil {
+		t.Fatal("expect validation error")
+	}
+	for _, entry := range []string{"conns.a: ", "routes.r1.conn: ", "routes.r2.replicas[1]: ", "routes.r3: "} {
+		if !strings.Contains(err.Error(), entry) {
+			t.Fatalf("expect error for %s, got %v", entry, err)
+		}
+	}
+}
+
+func TestTopologyConnConfFieldNames(t *testing.T) {
+	topo, err := LoadTopology([]byte(`
+conns:
+  main:
+    servers: ["127.0.0.1:6379"]
+    idle_count: 4
+    max_conn_pool_size: 16
+    tls: {server_name: redis.local}
+    retry: {max_attempts: 5}
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := topo.Conns["main"]
+	if conf.IdleCount != 4 || conf.MaxConnPoolSize != 16 || conf.TLS.ServerName != "redis.local" || conf.Retry.MaxAttempts != 5 {
+		t.Fatalf("unexpected conn conf %+v", conf)
+	}
+
+	data, err := json.Marshal(topo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"idle_count":4`) {
+		t.Fatalf("expect snake_case topology, got %s", data)
+	}
+
+	// ConnConf单独编解码时仍使用Go字段名
+	conf = &ConnConf{}
+	if err = json.Unmarshal([]byte(`{"IdleCount": 4, "MaxConnPoolSize": 16, "EnabledCluster": true}`), conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.IdleCount != 4 || conf.MaxConnPoolSize != 16 || !conf.EnabledCluster {
+		t.Fatalf("unexpected conn conf %+v", conf)
+	}
+}
+
+// TestConnConfDocFields connConfDoc是ConnConf的手工副本, ConnConf增加字段而没有同步时失败
+func TestConnConfDocFields(t *testing.T) {
+	fieldNames := func(typ reflect.Type) []string {
+		names := make([]string, 0, typ.NumField())
+		for i := 0; i < typ.NumField(); i++ {
+			names = append(names, typ.Field(i).Name)
+		}
+		return names
+	}
+	for _, pair := range [][2]reflect.Type{
+		{reflect.TypeOf(ConnConf{}), reflect.TypeOf(connConfDoc{})},
+		{reflect.TypeOf(TLSConf{}), reflect.TypeOf(tlsConfDoc{})},
+		{reflect.TypeOf(RetryPolicy{}), reflect.TypeOf(retryPolicyDoc{})},
+	} {
+		if a, b := fieldNames(pair[0]), fieldNames(pair[1]); !slices.Equal(a, b) {
+			t.Fatalf("%s fields %v, %s fields %v", pair[0], a, pair[1], b)
+		}
+	}
+
+	// 所有字段都填上非零值, 经过connConfDoc往返后不能丢失
+	conf := &ConnConf{}
+	fillNonZero(reflect.ValueOf(conf).Elem())
+	if got := newConnConfDoc(conf).connConf(); !reflect.DeepEqual(got, conf) {
+		t.Fatalf("conn conf lost fields through connConfDoc, got %+v, want %+v", got, conf)
+	}
+}
+
+func fillNonZero(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fillNonZero(v.Field(i))
+		}
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillNonZero(v.Elem())
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fillNonZero(v.Index(0))
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Float64:
+		v.SetFloat(0.5)
+	}
+}
@@ -1,8 +1,13 @@
 package routeredis
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -15,14 +20,101 @@ type ConnConf struct {
 	MaxConnLifetimeMillSec int
 	MaxConnPoolSize        int      // 最大链接数量
 	Servers                []string // 非分片集群模式下只能配置一个server
+	Username               string   // ACL用户名, 为空时使用default用户
 	Password               string
+	DB                     int      // 建立连接时SELECT的逻辑库, 集群模式只支持0号库
+	TLS                    *TLSConf // 不为空时使用TLS连接, 哨兵与集群模式同样生效
 	EnabledCluster         bool
+	SentinelAddrs          []string // 配置后以哨兵模式连接, 忽略Servers
+	SentinelMasterName     string
+	SentinelPassword       string
+	ReadFromReplicas       bool         // 哨兵模式下只读命令发往从库
+	KeyPrefix              string       // 连接下所有key的前缀, 用于多个环境共用redis
+	Retry                  *RetryPolicy // 临时错误的重试策略, 为空时不重试, 集群模式仍跟随MOVED/ASK
+}
+
+type TLSConf struct {
+	CAFile             string // 为空时使用系统根证书
+	CertFile           string // 客户端证书, 与KeyFile同时配置
+	KeyFile            string
+	ServerName         string // 为空时取连接地址中的host
+	InsecureSkipVerify bool
+}
+
+func (c *TLSConf) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		ca, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, ErrInvalidTLSCA
+		}
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, ErrIncompleteTLSKeyPair
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// tlsDialOptions 哨兵连接复用该配置, 但不使用数据节点的账号密码
+func (c *ConnConf) tlsDialOptions() ([]redis.DialOption, error) {
+	if c.TLS == nil {
+		return nil, nil
+	}
+
+	tlsConfig, err := c.TLS.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return []redis.DialOption{
+		redis.DialUseTLS(true),
+		redis.DialTLSConfig(tlsConfig),
+		redis.DialTLSSkipVerify(c.TLS.InsecureSkipVerify),
+	}, nil
+}
+
+func (c *ConnConf) dialOptions() ([]redis.DialOption, error) {
+	opts, err := c.tlsDialOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Password != "" {
+		opts = append(opts, redis.DialUsername(c.Username), redis.DialPassword(c.Password))
+	}
+
+	if c.DB != 0 {
+		opts = append(opts, redis.DialDatabase(c.DB))
+	}
+
+	return opts, nil
 }
 
 var (
+	ErrInvalidTLSCA               = errors.New("no valid certificate found in tls ca file")
+	ErrIncompleteTLSKeyPair       = errors.New("tls cert file and key file must be set together")
 	ErrRedisConnPoolNotRegistered = errors.New("redis conn pool not registered")
 	ErrRedisKeyRouteNotRegistered = errors.New("redis key route not registered")
 	ErrNoServerAvailable          = errors.New("no server is available")
+	ErrClusterDBNotSupported      = errors.New("redis cluster only supports db 0")
 )
 
 const DefaultConnName = "default"
@@ -32,58 +124,142 @@ type RedisPool interface {
 	Close() error
 }
 
-var _ RedisPool = (*RedisCluster)(nil)
+// ContextRedisPool 可选接口, 实现了该接口的连接池在获取连接时会遵循ctx的取消与超时
+type ContextRedisPool interface {
+	RedisPool
+	GetContext(ctx context.Context) (redis.Conn, error)
+}
+
+var (
+	_ RedisPool        = (*RedisCluster)(nil)
+	_ ContextRedisPool = (*RedisCluster)(nil)
+	_ ContextRedisPool = (*redis.Pool)(nil)
+)
 
 type RedisCluster struct {
 	*redisc.Cluster
+	retry     *RetryPolicy // 决定redisc跟随MOVED/ASK的次数以及TRYAGAIN的等待, 为空时取默认值
+	slotMu    sync.RWMutex
+	slotNodes []clusterSlotNode
 }
 
+func (r *RedisCluster) Get() redis.Conn {
+	conn, _ := redisc.RetryConn(r.Cluster.Get(), r.retry.clusterMaxRedirects(), r.retry.clusterTryAgainDelay())
+	return &ClusterConn{Conn: conn}
+}
+
+func (r *RedisCluster) GetContext(ctx context.Context) (redis.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.Get(), nil
+}
+
+var _ redis.ConnWithContext = (*ClusterConn)(nil)
+
+// ClusterConn redisc的重试连接不支持ConnWithContext, 这里通过后台执行命令来响应ctx的取消,
+// 被放弃的命令执行完毕后才会真正归还连接, 避免同一条连接被并发使用
 type ClusterConn struct {
 	redis.Conn
+	pending   sync.WaitGroup
+	abandoned atomic.Bool
 }
 
-func (r *RedisCluster) Get() redis.Conn {
-	conn, _ := redisc.RetryConn(r.Cluster.Get(), 10, 10*time.Millisecond)
-	return &ClusterConn{conn}
+func (c *ClusterConn) DoContext(ctx context.Context, cmd string, args ...any) (any, error) {
+	return c.withContext(ctx, func() (any, error) {
+		return c.Conn.Do(cmd, args...)
+	})
+}
+
+func (c *ClusterConn) DoWithTimeout(timeout time.Duration, cmd string, args ...any) (any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.DoContext(ctx, cmd, args...)
+}
+
+func (c *ClusterConn) ReceiveContext(ctx context.Context) (any, error) {
+	return c.withContext(ctx, c.Conn.Receive)
+}
+
+func (c *ClusterConn) ReceiveWithTimeout(timeout time.Duration) (any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.ReceiveContext(ctx)
+}
+
+func (c *ClusterConn) withContext(ctx context.Context, fn func() (any, error)) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	type result struct {
+		reply any
+		err   error
+	}
+	resCh := make(chan result, 1)
+	c.pending.Add(1)
+	go func() {
+		defer c.pending.Done()
+		reply, err := fn()
+		resCh <- result{reply, err}
+	}()
+
+	select {
+	case res := <-resCh:
+		return res.reply, res.err
+	case <-ctx.Done():
+		c.abandoned.Store(true)
+		return nil, ctx.Err()
+	}
 }
 
-var redisPools sync.Map
+func (c *ClusterConn) Close() error {
+	if !c.abandoned.Load() {
+		return c.Conn.Close()
+	}
+
+	go func() {
+		c.pending.Wait()
+		_ = c.Conn.Close()
+	}()
+
+	return nil
+}
 
 func ConnectByConf(connName string, conf *ConnConf) error {
+	return defaultClient.ConnectByConf(connName, conf)
+}
+
+func (c *Client) ConnectByConf(connName string, conf *ConnConf) error {
 	if conf.EnabledCluster {
-		return ConnectClusterByConf(connName, conf)
+		return c.ConnectClusterByConf(connName, conf)
 	}
 
-	pool := &redis.Pool{
-		MaxIdle:         conf.IdleCount,
-		MaxActive:       conf.MaxConnPoolSize, //when zero,there's no limit. https://godoc.org/github.com/garyburd/redigo/redis#Pool
-		IdleTimeout:     time.Duration(conf.IdleTimeoutMillSec) * time.Millisecond,
-		MaxConnLifetime: time.Duration(conf.MaxConnLifetimeMillSec) * time.Millisecond,
-		Wait:            true,
-		Dial: func() (redis.Conn, error) {
-			if len(conf.Servers) == 0 {
-				return nil, ErrNoServerAvailable
-			}
+	if len(conf.SentinelAddrs) > 0 {
+		return c.ConnectSentinelByConf(connName, conf)
+	}
 
-			server := conf.Servers[0]
-			c, err := redis.Dial("tcp", server)
-			if err != nil {
-				return nil, err
-			}
+	dialOpts, err := conf.dialOptions()
+	if err != nil {
+		return err
+	}
 
-			if conf.Password != "" {
-				if _, err := c.Do("AUTH", conf.Password); err != nil {
-					c.Close()
-					return nil, err
-				}
-			}
+	pool := newRedisPool(conf)
+	pool.Dial = func() (redis.Conn, error) {
+		if len(conf.Servers) == 0 {
+			return nil, ErrNoServerAvailable
+		}
 
-			return c, nil
-		},
-		TestOnBorrow: redisOnBorrow,
+		return redis.Dial("tcp", conf.Servers[0], dialOpts...)
 	}
 
-	Connect(connName, pool)
+	c.SetConnKeyPrefix(connName, conf.KeyPrefix)
+	c.SetConnRetryPolicy(connName, conf.Retry)
+	c.Connect(connName, pool)
 
 	return nil
 }
@@ -93,46 +269,52 @@ func ConnectDefaultByConf(conf *ConnConf) error {
 }
 
 func ConnectClusterByConf(connName string, conf *ConnConf) error {
+	return defaultClient.ConnectClusterByConf(connName, conf)
+}
+
+func (c *Client) ConnectClusterByConf(connName string, conf *ConnConf) error {
+	if conf.DB != 0 {
+		return ErrClusterDBNotSupported
+	}
+
+	dialOpts, err := conf.dialOptions()
+	if err != nil {
+		return err
+	}
+
 	cluster := &redisc.Cluster{
 		StartupNodes: conf.Servers,
-		DialOptions: []redis.DialOption{
-			redis.DialPassword(conf.Password),
-		},
+		DialOptions:  dialOpts,
 		CreatePool: func(addr string, opts ...redis.DialOption) (*redis.Pool, error) {
-			pool := &redis.Pool{
-				MaxIdle:         conf.IdleCount,
-				MaxActive:       conf.MaxConnPoolSize, //when zero,there's no limit. https://godoc.org/github.com/garyburd/redigo/redis#Pool
-				IdleTimeout:     time.Duration(conf.IdleTimeoutMillSec) * time.Millisecond,
-				MaxConnLifetime: time.Duration(conf.MaxConnLifetimeMillSec) * time.Millisecond,
-				Wait:            true,
-				TestOnBorrow:    redisOnBorrow,
-			}
+			pool := newRedisPool(conf)
 			pool.Dial = func() (redis.Conn, error) {
-				conn, err := redis.Dial("tcp", addr, opts...)
-				if err != nil {
-					return nil, err
-				}
-
-				if conf.Password != "" {
-					if _, err := conn.Do("AUTH", conf.Password); err != nil {
-						conn.Close()
-						return nil, err
-					}
-				}
-
-				return conn, nil
+				return redis.Dial("tcp", addr, opts...)
 			}
 			return pool, nil
 		},
 	}
 
-	return ConnectCluster(connName, cluster)
+	c.SetConnKeyPrefix(connName, conf.KeyPrefix)
+	c.SetConnRetryPolicy(connName, conf.Retry)
+
+	return c.connectCluster(connName, &RedisCluster{Cluster: cluster, retry: conf.Retry})
 }
 
 func ConnectDefaultClusterByConf(conf *ConnConf) error {
 	return ConnectClusterByConf(DefaultConnName, conf)
 }
 
+func newRedisPool(conf *ConnConf) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:         conf.IdleCount,
+		MaxActive:       conf.MaxConnPoolSize, //when zero,there's no limit. https://godoc.org/github.com/garyburd/redigo/redis#Pool
+		IdleTimeout:     time.Duration(conf.IdleTimeoutMillSec) * time.Millisecond,
+		MaxConnLifetime: time.Duration(conf.MaxConnLifetimeMillSec) * time.Millisecond,
+		Wait:            true,
+		TestOnBorrow:    redisOnBorrow,
+	}
+}
+
 func redisOnBorrow(c redis.Conn, t time.Time) error {
 	if time.Since(t) < time.Minute {
 		return nil
@@ -142,14 +324,36 @@ func redisOnBorrow(c redis.Conn, t time.Time) error {
 }
 
 func Connect(connName string, redisPool RedisPool) {
-	oldPoolAny, ok := redisPools.Load(connName)
+	defaultClient.Connect(connName, redisPool)
+}
+
+// Connect 注册连接池, 同名的旧连接池在借出的连接全部归还或者超过PoolDrainTimeout后关闭
+func (c *Client) Connect(connName string, redisPool RedisPool) {
+	oldPool, ok := c.redisPools.Swap(connName, redisPool)
 	if ok {
-		go func() {
-			time.Sleep(time.Second * 5)
-			_ = oldPoolAny.(RedisPool).Close()
-		}()
+		c.drainPool(oldPool.(RedisPool))
+		c.notifyConnSwapped(connName)
+	}
+}
+
+type connSwapListener struct {
+	fn func(connName string)
+}
+
+// addConnSwapListener 连接池被Connect替换后回调fn, 返回的函数用于移除监听
+func (c *Client) addConnSwapListener(fn func(connName string)) func() {
+	listener := &connSwapListener{fn: fn}
+	c.connSwapListeners.Store(listener, struct{}{})
+	return func() {
+		c.connSwapListeners.Delete(listener)
 	}
-	redisPools.Store(connName, redisPool)
+}
+
+func (c *Client) notifyConnSwapped(connName string) {
+	c.connSwapListeners.Range(func(key, _ any) bool {
+		key.(*connSwapListener).fn(connName)
+		return true
+	})
 }
 
 func ConnectDefault(redisPool RedisPool) {
@@ -157,11 +361,19 @@ func ConnectDefault(redisPool RedisPool) {
 }
 
 func ConnectCluster(connName string, redisCluster *redisc.Cluster) error {
+	return defaultClient.ConnectCluster(connName, redisCluster)
+}
+
+func (c *Client) ConnectCluster(connName string, redisCluster *redisc.Cluster) error {
+	return c.connectCluster(connName, &RedisCluster{Cluster: redisCluster})
+}
+
+func (c *Client) connectCluster(connName string, redisCluster *RedisCluster) error {
 	if err := redisCluster.Refresh(); err != nil {
 		return err
 	}
 
-	Connect(connName, &RedisCluster{redisCluster})
+	c.Connect(connName, redisCluster)
 
 	return nil
 }
@@ -175,7 +387,11 @@ func GetDefaultConnPool() (RedisPool, error) {
 }
 
 func GetConnPool(connName string) (RedisPool, error) {
-	redisPool, ok := redisPools.Load(connName)
+	return defaultClient.GetConnPool(connName)
+}
+
+func (c *Client) GetConnPool(connName string) (RedisPool, error) {
+	redisPool, ok := c.redisPools.Load(connName)
 	if !ok {
 		return nil, ErrRedisConnPoolNotRegistered
 	}
@@ -187,31 +403,71 @@ func GetDefaultConn() (redis.Conn, error) {
 }
 
 func GetConn(connName string) (redis.Conn, error) {
-	pool, err := GetConnPool(connName)
+	return defaultClient.GetConn(connName)
+}
+
+func (c *Client) GetConn(connName string) (redis.Conn, error) {
+	pool, err := c.GetConnPool(connName)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Get(), nil
+}
+
+func GetDefaultConnContext(ctx context.Context) (redis.Conn, error) {
+	return GetConnContext(ctx, DefaultConnName)
+}
+
+func GetConnContext(ctx context.Context, connName string) (redis.Conn, error) {
+	return defaultClient.GetConnContext(ctx, connName)
+}
+
+// GetConnContext 获取连接, 连接池配置了Wait时等待空闲连接的过程可被ctx取消
+func (c *Client) GetConnContext(ctx context.Context, connName string) (redis.Conn, error) {
+	pool, err := c.GetConnPool(connName)
 	if err != nil {
 		return nil, err
 	}
+	return getPoolConnContext(ctx, pool)
+}
+
+func getPoolConnContext(ctx context.Context, pool RedisPool) (redis.Conn, error) {
+	if ctxPool, ok := pool.(ContextRedisPool); ok {
+		return ctxPool.GetContext(ctx)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return pool.Get(), nil
 }
 
-var _ RedisPool = (*DynamicConnPool)(nil)
+var _ ContextRedisPool = (*DynamicConnPool)(nil)
 
 func NewDynamicConnPool(connName string) (*DynamicConnPool, error) {
-	if _, err := GetConnPool(connName); err != nil {
+	return defaultClient.NewDynamicConnPool(connName)
+}
+
+// NewDynamicConnPool 每次取连接时按连接名查找c中当前注册的连接池
+func (c *Client) NewDynamicConnPool(connName string) (*DynamicConnPool, error) {
+	if _, err := c.GetConnPool(connName); err != nil {
 		return nil, err
 	}
 
 	return &DynamicConnPool{
+		client:   c,
 		connName: connName,
 	}, nil
 }
 
 type DynamicConnPool struct {
+	client   *Client
 	connName string
 }
 
 func (d *DynamicConnPool) Get() redis.Conn {
-	conn, err := GetConn(d.connName)
+	conn, err := d.client.GetConn(d.connName)
 	if err != nil {
 		return NewErrConn(err)
 	}
@@ -219,8 +475,12 @@ func (d *DynamicConnPool) Get() redis.Conn {
 	return conn
 }
 
+func (d *DynamicConnPool) GetContext(ctx context.Context) (redis.Conn, error) {
+	return d.client.GetConnContext(ctx, d.connName)
+}
+
 func (d *DynamicConnPool) Close() error {
-	pool, err := GetConnPool(d.connName)
+	pool, err := d.client.GetConnPool(d.connName)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,246 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestConnSelectDB(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		selected []string
+	)
+	addr := newFakeRedis(t, func(args []string) any {
+		if args[0] == "SELECT" {
+			mu.Lock()
+			selected = append(selected, args[1])
+			mu.Unlock()
+		}
+		return "OK"
+	})
+	sentinel := newFakeRedis(t, func(args []string) any {
+		if len(args) == 3 && args[1] == "get-master-addr-by-name" {
+			host, port, _ := net.SplitHostPort(addr)
+			return []any{host, port}
+		}
+		return redis.Error("ERR unknown command")
+	})
+
+	c := NewClient()
+	if err := c.ConnectByConf("standalone", &ConnConf{Servers: []string{addr}, DB: 3}); err != nil {
+		t.Fatal(err)
+	}
+	err := c.ConnectByConf("sentinel", &ConnConf{SentinelAddrs: []string{sentinel}, SentinelMasterName: "mymaster", DB: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("db.standalone", "standalone")
+	c.RegisterKeyRoute("db.sentinel", "sentinel")
+
+	for _, route := range []string{"db.standalone", "db.sentinel"} {
+		if _, err = DoCmdWithTTL(nil, "GET", c.NewKey(route, "k")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(selected) != 2 || selected[0] != "3" || selected[1] != "5" {
+		t.Fatalf("expect SELECT 3 and SELECT 5 on dial, got %v", selected)
+	}
+
+	err = c.ConnectByConf("cluster", &ConnConf{Servers: []string{addr}, EnabledCluster: true, DB: 1})
+	if !errors.Is(err, ErrClusterDBNotSupported) {
+		t.Fatalf("expect ErrClusterDBNotSupported, got %v", err)
+	}
+}
+
+func TestConnAuth(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		auth []string
+	)
+	addr := newFakeRedis(t, func(args []string) any {
+		if args[0] == "AUTH" {
+			mu.Lock()
+			auth = args[1:]
+			mu.Unlock()
+		}
+		return "OK"
+	})
+
+	c := NewClient()
+	if err := c.ConnectByConf("acl", &ConnConf{Servers: []string{addr}, Username: "app", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("acl", "acl")
+	if _, err := DoCmdWithTTL(nil, "GET", c.NewKey("acl", "k")); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(auth, []string{"app", "secret"}) {
+		t.Fatalf("expect AUTH app secret, got %v", auth)
+	}
+}
+
+func TestConnTLSConf(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient()
+	err := c.ConnectByConf("tls", &ConnConf{Servers: []string{"127.0.0.1:1"}, TLS: &TLSConf{CAFile: caFile}})
+	if !errors.Is(err, ErrInvalidTLSCA) {
+		t.Fatalf("expect ErrInvalidTLSCA, got %v", err)
+	}
+
+	// 客户端证书和私钥必须同时配置
+	for _, conf := range []*TLSConf{{CertFile: caFile}, {KeyFile: caFile}} {
+		if err = c.ConnectByConf("tls", &ConnConf{Servers: []string{"127.0.0.1:1"}, TLS: conf}); !errors.Is(err, ErrIncompleteTLSKeyPair) {
+			t.Fatalf("expect ErrIncompleteTLSKeyPair for %+v, got %v", conf, err)
+		}
+	}
+	if len(c.ConnNames()) != 0 {
+		t.Fatalf("expect no conn registered, got %v", c.ConnNames())
+	}
+}
+
+func TestConnContext(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() {
+		close(release)
+	})
+	addr := newFakeRedis(t, func(args []string) any {
+		if args[0] == "BLPOP" {
+			<-release
+		}
+		return "OK"
+	})
+
+	c := NewClient()
+	if err := c.ConnectByConf("ctx", &ConnConf{Servers: []string{addr}, MaxConnPoolSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("ctx", "ctx")
+	key := c.NewKey("ctx", "k")
+
+	// 命令执行中ctx超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := DoCmdWithTTLContext(ctx, nil, "BLPOP", key, 0); err == nil || time.Since(start) > time.Second {
+		t.Fatalf("expect slow command aborted by ctx, got %v after %v", err, time.Since(start))
+	}
+
+	// 连接池耗尽时等待连接的过程中ctx取消
+	pool, err := c.GetConnPool("ctx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := pool.Get()
+	defer held.Close()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start = time.Now()
+	if _, err = DoCmdWithTTLContext(ctx, nil, "GET", key); !errors.Is(err, context.Canceled) || time.Since(start) > time.Second {
+		t.Fatalf("expect pool wait aborted by ctx, got %v after %v", err, time.Since(start))
+	}
+}
+
+// blockingConn Do阻塞到unblock关闭, 记录Close调用
+type blockingConn struct {
+	redis.Conn
+	unblock chan struct{}
+	closed  chan struct{}
+}
+
+func (c *blockingConn) Do(string, ...any) (any, error) {
+	<-c.unblock
+	return "OK", nil
+}
+
+func (c *blockingConn) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestClusterConnAbandoned(t *testing.T) {
+	raw := &blockingConn{unblock: make(chan struct{}), closed: make(chan struct{})}
+	conn := &ClusterConn{Conn: raw}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := conn.DoContext(ctx, "GET", "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+
+	_ = conn.Close()
+	select {
+	case <-raw.closed:
+		t.Fatal("expect abandoned conn kept open until the pending command finishes")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(raw.unblock)
+	select {
+	case <-raw.closed:
+	case <-time.After(time.Second):
+		t.Fatal("expect conn closed after the pending command finished")
+	}
+}
+
+// brokenConn Err返回取连接时的错误, 记录Close调用
+type brokenConn struct {
+	redis.Conn
+	closed bool
+}
+
+func (c *brokenConn) Err() error {
+	return errors.New("dial failed")
+}
+
+func (c *brokenConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+type brokenPool struct {
+	conns []*brokenConn
+}
+
+func (p *brokenPool) Get() redis.Conn {
+	conn := &brokenConn{}
+	p.conns = append(p.conns, conn)
+	return conn
+}
+
+func (p *brokenPool) Close() error {
+	return nil
+}
+
+func TestSendCmdClosesBrokenConn(t *testing.T) {
+	c := NewClient()
+	pool := &brokenPool{}
+	c.Connect("main", pool)
+	c.RegisterKeyRoute("user", "main")
+
+	if err := SendCmdWithTTL(nil, "SET", c.NewKey("user", "1"), "v"); err == nil {
+		t.Fatal("expect conn error")
+	}
+	if len(pool.conns) != 1 || !pool.conns[0].closed {
+		t.Fatal("expect broken conn closed")
+	}
+}
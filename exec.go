@@ -1,6 +1,7 @@
 package routeredis
 
 import (
+	"context"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -8,6 +9,7 @@ import (
 
 type OnCmdDoneFunc func(execCmdWay string, ttl *TTL, cost time.Duration, cmd string, key *Key, err error, args ...any)
 
+// OnCmdDone DefaultClient的命令回调, 其他Client通过Client.SetOnCmdDone设置
 var OnCmdDone OnCmdDoneFunc
 
 type TTL struct {
@@ -16,6 +18,13 @@ type TTL struct {
 	IsMillisecond bool
 }
 
+func (t *TTL) expireCmd() string {
+	if t.IsMillisecond {
+		return "PEXPIRE"
+	}
+	return "EXPIRE"
+}
+
 func NewTTL(isAsyncTTL bool, ttl int64, isMillisecond bool) *TTL {
 	return &TTL{
 		IsAsyncTTL:    isAsyncTTL,
@@ -60,46 +69,64 @@ func NewMilliSecTTL(isAsyncTTL bool, ttl int64) *TTL {
 	return NewTTL(isAsyncTTL, ttl, true)
 }
 
-func DoCmdWithTTL(ttl *TTL, cmd string, key *Key, args ...any) (res any, err error) {
-	conn, err := RouteConn(key.Route)
-	if err != nil {
-		return nil, err
-	}
+func DoCmdWithTTL(ttl *TTL, cmd string, key *Key, args ...any) (any, error) {
+	return DoCmdWithTTLContext(context.Background(), ttl, cmd, key, args...)
+}
 
-	if OnCmdDone != nil {
+// DoCmdWithTTLContext 同DoCmdWithTTL, ctx作用于路由取连接(含连接池等待)以及命令执行的整个过程,
+// 路由配置了从库时只读命令发往从库, 可以通过WithReadFromPrimary指定读主库
+func DoCmdWithTTLContext(ctx context.Context, ttl *TTL, cmd string, key *Key, args ...any) (res any, err error) {
+	c := key.Client()
+	if onCmdDone := c.cmdDoneHook(); onCmdDone != nil {
 		start := time.Now()
 		defer func() {
-			OnCmdDone("DoCmdWithTTL", ttl, time.Since(start), cmd, key, err, args...)
+			onCmdDone(CmdWayDo, ttl, time.Since(start), cmd, key, err, args...)
 		}()
 	}
 
-	if err = conn.Err(); err != nil {
-		return 0, err
-	}
+	return c.cmdHandler(key.Route, doCmd)(ctx, &Cmd{Way: CmdWayDo, TTL: ttl, Name: cmd, Key: key, Args: args})
+}
 
-	res, err = doCmdWithTTL(conn, ttl, cmd, key.Key, args...)
-	if err != nil {
-		return nil, err
+// doCmd 中间件链最内层的DoCmdWithTTL, 连接配置了RetryPolicy时遇到临时错误重新取连接执行
+func doCmd(ctx context.Context, cmd *Cmd) (any, error) {
+	if cmd.TTL == nil && IsReadOnlyCmd(cmd.Name) {
+		if res, ok, err := doReplicaCmd(ctx, cmd.Key, cmd.Name, cmd.Args...); ok {
+			return res, err
+		}
 	}
 
-	return res, nil
+	return doCmdWithRetry(ctx, cmd.Key, cmd.Name, cmd.Args, func() (any, bool, error) {
+		conn, err := KeyConnContext(ctx, cmd.Key)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if err = conn.Err(); err != nil {
+			conn.Close()
+			return nil, false, err
+		}
+
+		res, err := doCmdWithTTL(ctx, conn, cmd.TTL, cmd.Name, cmd.Key.RedisKey(), cmd.Args...)
+		if err != nil {
+			return nil, true, err
+		}
+
+		return res, true, nil
+	})
 }
 
-func doCmdWithTTL(conn redis.Conn, ttl *TTL, cmd string, key string, args ...any) (any, error) {
+func doCmdWithTTL(ctx context.Context, conn redis.Conn, ttl *TTL, cmd string, key string, args ...any) (any, error) {
 	defer conn.Close()
 
-	reply, err := conn.Do(cmd, append([]any{key}, args...)...)
+	reply, err := doContext(ctx, conn, cmd, append([]any{key}, args...)...)
 	if err == nil {
 		if ttl != nil && ttl.TTL > 0 {
-			setTTLCmd := "EXPIRE"
-			if ttl.IsMillisecond {
-				setTTLCmd = "PEXPIRE"
-			}
+			setTTLCmd := ttl.expireCmd()
 			if !ttl.IsAsyncTTL {
-				_, _ = conn.Do(setTTLCmd, key, ttl.TTL)
+				_, _ = doContext(ctx, conn, setTTLCmd, key, ttl.TTL)
 			} else {
 				if _, ok := conn.(*ClusterConn); ok {
-					_, _ = conn.Do(setTTLCmd, key, ttl.TTL)
+					_, _ = doContext(ctx, conn, setTTLCmd, key, ttl.TTL)
 				} else {
 					_ = conn.Send(setTTLCmd, key, ttl.TTL)
 				}
@@ -110,42 +137,77 @@ func doCmdWithTTL(conn redis.Conn, ttl *TTL, cmd string, key string, args ...any
 	return reply, err
 }
 
-func SendCmdWithTTL(ttl *TTL, cmd string, key *Key, args ...any) error {
-	conn, err := RouteConn(key.Route)
-	if err != nil {
-		return err
+// doContext 连接实现了ConnWithContext时按ctx的截止时间执行命令, 否则退化为普通的Do
+func doContext(ctx context.Context, conn redis.Conn, cmd string, args ...any) (any, error) {
+	if _, ok := conn.(redis.ConnWithContext); ok {
+		return redis.DoContext(conn, ctx, cmd, args...)
 	}
 
-	if OnCmdDone != nil {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return conn.Do(cmd, args...)
+}
+
+// receiveContext 连接实现了ConnWithContext时按ctx的截止时间读取应答, 否则退化为普通的Receive
+func receiveContext(ctx context.Context, conn redis.Conn) (any, error) {
+	if _, ok := conn.(redis.ConnWithContext); ok {
+		return redis.ReceiveContext(conn, ctx)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return conn.Receive()
+}
+
+func SendCmdWithTTL(ttl *TTL, cmd string, key *Key, args ...any) error {
+	return SendCmdWithTTLContext(context.Background(), ttl, cmd, key, args...)
+}
+
+// SendCmdWithTTLContext 同SendCmdWithTTL, ctx作用于路由取连接(含连接池等待), 集群模式下同时作用于命令执行
+func SendCmdWithTTLContext(ctx context.Context, ttl *TTL, cmd string, key *Key, args ...any) (err error) {
+	c := key.Client()
+	if onCmdDone := c.cmdDoneHook(); onCmdDone != nil {
 		start := time.Now()
 		defer func() {
-			OnCmdDone("SendCmdWithTTL", ttl, time.Since(start), cmd, key, err, args...)
+			onCmdDone(CmdWaySend, ttl, time.Since(start), cmd, key, err, args...)
 		}()
 	}
 
-	if err := conn.Err(); err != nil {
-		return err
+	_, err = c.cmdHandler(key.Route, sendCmd)(ctx, &Cmd{Way: CmdWaySend, TTL: ttl, Name: cmd, Key: key, Args: args})
+
+	return err
+}
+
+// sendCmd 中间件链最内层的SendCmdWithTTL
+func sendCmd(ctx context.Context, cmd *Cmd) (any, error) {
+	conn, err := KeyConnContext(ctx, cmd.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = conn.Err(); err != nil {
+		conn.Close()
+		return nil, err
 	}
 
 	if _, ok := conn.(*ClusterConn); ok {
-		_, err = doCmdWithTTL(conn, ttl, cmd, key.Key, args...)
-		if err != nil {
-			return err
-		}
+		_, err = doCmdWithTTL(ctx, conn, cmd.TTL, cmd.Name, cmd.Key.RedisKey(), cmd.Args...)
+		return nil, err
 	}
 
 	defer conn.Close()
 
-	err = conn.Send(cmd, append([]any{key.Key}, args...)...)
+	key := cmd.Key.RedisKey()
+	err = conn.Send(cmd.Name, append([]any{key}, cmd.Args...)...)
 	if err == nil {
-		if ttl != nil && ttl.TTL > 0 {
-			setTTLCmd := "EXPIRE"
-			if ttl.IsMillisecond {
-				setTTLCmd = "PEXPIRE"
-			}
-			_ = conn.Send(setTTLCmd, key.Key, ttl.TTL)
+		if cmd.TTL != nil && cmd.TTL.TTL > 0 {
+			_ = conn.Send(cmd.TTL.expireCmd(), key, cmd.TTL.TTL)
 		}
 	}
 
-	return err
+	return nil, err
 }
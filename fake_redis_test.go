@@ -0,0 +1,128 @@
+package routeredis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// newFakeRedis 启动一个进程内的RESP服务, handler返回值按类型编码为应答
+func newFakeRedis(t *testing.T, handler func(args []string) any) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeRedisConn(conn, handler)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func serveFakeRedisConn(conn net.Conn, handler func(args []string) any) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readFakeRedisCmd(r)
+		if err != nil {
+			return
+		}
+		args[0] = strings.ToUpper(args[0])
+		reply := handler(args)
+		if reply == fakeRedisCloseConn {
+			return
+		}
+		writeFakeRedisReply(w, reply)
+		if r.Buffered() == 0 {
+			if err = w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func readFakeRedisCmd(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, errors.New("fake redis: expect array")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+type fakeRedisSignal int
+
+// fakeRedisCloseConn handler返回该值时不应答直接断开连接, 用于模拟连接重置
+const fakeRedisCloseConn fakeRedisSignal = 1
+
+// fakeRedisReplies 一次命令写出多条应答, 用于模拟订阅连接上推送的消息
+type fakeRedisReplies []any
+
+func writeFakeRedisReply(w *bufio.Writer, reply any) {
+	switch v := reply.(type) {
+	case fakeRedisReplies:
+		for _, item := range v {
+			writeFakeRedisReply(w, item)
+		}
+	case nil:
+		_, _ = w.WriteString("$-1\r\n")
+	case redis.Error:
+		_, _ = fmt.Fprintf(w, "-%s\r\n", v)
+	case int:
+		_, _ = fmt.Fprintf(w, ":%d\r\n", v)
+	case int64:
+		_, _ = fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		_, _ = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []any:
+		_, _ = fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeFakeRedisReply(w, item)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// decodeAs 基础类型(包括以其为底层类型的自定义类型, 如type UID int64)按strconv解析, 其余类型按路由的Codec解码,
+// T为指针时分配指向的值后直接解码到该指针, 使ProtobufCodec等要求指针实现接口的Codec可以用于*T
+func decodeAs[T any](key *Key, data []byte) (T, error) {
+	var v T
+	rv := reflect.ValueOf(&v).Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(string(data))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(string(data))
+		if err != nil {
+			return v, err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(string(data), 10, rv.Type().Bits())
+		if err != nil {
+			return v, err
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(string(data), 10, rv.Type().Bits())
+		if err != nil {
+			return v, err
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(string(data), rv.Type().Bits())
+		if err != nil {
+			return v, err
+		}
+		rv.SetFloat(f)
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			return v, decodeValue(key, data, &v)
+		}
+		rv.SetBytes(data)
+	case reflect.Pointer:
+		v = reflect.New(rv.Type().Elem()).Interface().(T)
+		return v, decodeValue(key, data, v)
+	default:
+		return v, decodeValue(key, data, &v)
+	}
+	return v, nil
+}
+
+func decodeSliceAs[T any](key *Key, reply any, err error) ([]T, error) {
+	items, err := redis.ByteSlices(reply, err)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]T, 0, len(items))
+	for _, item := range items {
+		v, err := decodeAs[T](key, item)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+
+	return res, nil
+}
+
+func getAs[T any](ctx context.Context, key *Key, cmd string, args ...any) (T, bool, error) {
+	var v T
+	data, err := redis.Bytes(DoCmdWithTTLContext(ctx, nil, cmd, key, args...))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return v, false, nil
+		}
+		return v, false, err
+	}
+
+	if v, err = decodeAs[T](key, data); err != nil {
+		return v, false, err
+	}
+
+	return v, true, nil
+}
+
+func GetAs[T any](key *Key) (T, bool, error) {
+	return GetAsCtx[T](context.Background(), key)
+}
+
+func GetAsCtx[T any](ctx context.Context, key *Key) (T, bool, error) {
+	return getAs[T](ctx, key, "GET")
+}
+
+func HgetAs[T any](key *Key, field any) (T, bool, error) {
+	return HgetAsCtx[T](context.Background(), key, field)
+}
+
+func HgetAsCtx[T any](ctx context.Context, key *Key, field any) (T, bool, error) {
+	return getAs[T](ctx, key, "HGET", field)
+}
+
+func HgetallAs[K comparable, V any](key *Key) (map[K]V, bool, error) {
+	return HgetallAsCtx[K, V](context.Background(), key)
+}
+
+// HgetallAsCtx HGETALL对不存在的key返回空数组, 此时found为false, 与GetAs和HgetAs一致
+func HgetallAsCtx[K comparable, V any](ctx context.Context, key *Key) (map[K]V, bool, error) {
+	items, err := redis.ByteSlices(DoCmdWithTTLContext(ctx, nil, "HGETALL", key))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	m := make(map[K]V, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		k, err := decodeAs[K](key, items[i])
+		if err != nil {
+			return nil, false, err
+		}
+		v, err := decodeAs[V](key, items[i+1])
+		if err != nil {
+			return nil, false, err
+		}
+		m[k] = v
+	}
+
+	return m, len(m) > 0, nil
+}
+
+func LrangeAs[T any](key *Key, start, end int32) ([]T, error) {
+	return LrangeAsCtx[T](context.Background(), key, start, end)
+}
+
+func LrangeAsCtx[T any](ctx context.Context, key *Key, start, end int32) ([]T, error) {
+	reply, err := DoCmdWithTTLContext(ctx, nil, "LRANGE", key, start, end)
+	return decodeSliceAs[T](key, reply, err)
+}
+
+func SmembersAs[T any](key *Key) ([]T, error) {
+	return SmembersAsCtx[T](context.Background(), key)
+}
+
+func SmembersAsCtx[T any](ctx context.Context, key *Key) ([]T, error) {
+	reply, err := DoCmdWithTTLContext(ctx, nil, "SMEMBERS", key)
+	return decodeSliceAs[T](key, reply, err)
+}
+
+func ZrangeAs[T any](key *Key, start, end any) ([]T, error) {
+	return ZrangeAsCtx[T](context.Background(), key, start, end)
+}
+
+func ZrangeAsCtx[T any](ctx context.Context, key *Key, start, end any) ([]T, error) {
+	reply, err := DoCmdWithTTLContext(ctx, nil, "ZRANGE", key, start, end)
+	return decodeSliceAs[T](key, reply, err)
+}
+
+func ZrevrangeAs[T any](key *Key, start, end any) ([]T, error) {
+	return ZrevrangeAsCtx[T](context.Background(), key, start, end)
+}
+
+func ZrevrangeAsCtx[T any](ctx context.Context, key *Key, start, end any) ([]T, error) {
+	reply, err := DoCmdWithTTLContext(ctx, nil, "ZREVRANGE", key, start, end)
+	return decodeSliceAs[T](key, reply, err)
+}
@@ -0,0 +1,106 @@
+package routeredis
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestGenericAccessors(t *testing.T) {
+	addr := newFakeRedis(t, func(args []string) any {
+		switch args[0] {
+		case "GET":
+			return `{"Id":1,"Name":"foo"}`
+		case "HGET":
+			return "42"
+		case "HGETALL":
+			if args[1] == "missing" {
+				return []any{}
+			}
+			return []any{"1", "10", "2", "20"}
+		case "LRANGE", "SMEMBERS", "ZRANGE":
+			return []any{"1", "2", "3"}
+		}
+		return nil
+	})
+
+	const route = "generic"
+	ConnectByConf(route, &ConnConf{Servers: []string{addr}})
+	RegisterKeyRoute(route, route)
+	key := NewKey(route, "foo")
+
+	type user struct {
+		Id   int
+		Name string
+	}
+	u, ok, err := GetAs[user](key)
+	if err != nil || !ok || u.Name != "foo" {
+		t.Fatalf("GetAs: %+v %v %v", u, ok, err)
+	}
+
+	n, ok, err := HgetAs[uint16](key, "f")
+	if err != nil || !ok || n != 42 {
+		t.Fatalf("HgetAs: %v %v %v", n, ok, err)
+	}
+
+	m, ok, err := HgetallAs[uint64, int64](key)
+	if err != nil || !ok || len(m) != 2 || m[2] != 20 {
+		t.Fatalf("HgetallAs: %v %v %v", m, ok, err)
+	}
+
+	if m, ok, err = HgetallAs[uint64, int64](NewKey(route, "missing")); err != nil || ok || len(m) != 0 {
+		t.Fatalf("HgetallAs missing key: %v %v %v", m, ok, err)
+	}
+	// 已废弃的包装函数保持原有语义, 空hash也返回true
+	if m, ok, err = HgetallMapUint64ToInt64(NewKey(route, "missing")); err != nil || !ok || m == nil {
+		t.Fatalf("HgetallMapUint64ToInt64 missing key: %v %v %v", m, ok, err)
+	}
+
+	// 以基础类型为底层类型的自定义类型同样按strconv解析
+	type uid int64
+	id, ok, err := HgetAs[uid](key, "f")
+	if err != nil || !ok || id != 42 {
+		t.Fatalf("HgetAs named type: %v %v %v", id, ok, err)
+	}
+
+	if _, ok, err = HgetAs[bool](key, "f"); err == nil {
+		t.Fatal("expect parse error for bool")
+	}
+
+	for _, fn := range []func() ([]int32, error){
+		func() ([]int32, error) { return LrangeAs[int32](key, 0, -1) },
+		func() ([]int32, error) { return SmembersAs[int32](key) },
+		func() ([]int32, error) { return ZrangeAs[int32](key, 0, -1) },
+	} {
+		items, err := fn()
+		if err != nil || len(items) != 3 || items[2] != 3 {
+			t.Fatalf("expect [1 2 3], got %v %v", items, err)
+		}
+	}
+}
+
+func TestGenericAccessorsProtobuf(t *testing.T) {
+	data, err := proto.Marshal(wrapperspb.String("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := newFakeRedis(t, func(args []string) any {
+		if args[0] == "GET" {
+			return string(data)
+		}
+		return nil
+	})
+
+	c := NewClient()
+	if err = c.ConnectByConf("main", &ConnConf{Servers: []string{addr}}); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("generic.pb", "main")
+	c.SetRouteCodec("generic.pb", ProtobufCodec)
+
+	msg, ok, err := GetAs[*wrapperspb.StringValue](c.NewKey("generic.pb", "foo"))
+	if err != nil || !ok || msg.GetValue() != "foo" {
+		t.Fatalf("GetAs: %v %v %v", msg, ok, err)
+	}
+}
@@ -1,14 +1,18 @@
 package routeredis
 
 import (
+	"context"
 	"errors"
 
 	"github.com/gomodule/redigo/redis"
-	jsoniter "github.com/json-iterator/go"
 )
 
 func Hget(key *Key, field any) (string, bool, error) {
-	res, err := redis.String(DoCmdWithTTL(nil, "HGET", key, field))
+	return HgetCtx(context.Background(), key, field)
+}
+
+func HgetCtx(ctx context.Context, key *Key, field any) (string, bool, error) {
+	res, err := redis.String(DoCmdWithTTLContext(ctx, nil, "HGET", key, field))
 	if err != nil {
 		if errors.Is(err, redis.ErrNil) {
 			return "", false, nil
@@ -18,31 +22,33 @@ func Hget(key *Key, field any) (string, bool, error) {
 	return res, true, nil
 }
 
+// Deprecated: 使用HgetAs[int64]
 func HgetInt64(key *Key, field any) (int64, bool, error) {
-	res, err := redis.Int64(DoCmdWithTTL(nil, "HGET", key, field))
-	if err != nil {
-		if errors.Is(err, redis.ErrNil) {
-			return 0, false, nil
-		}
-		return 0, false, err
-	}
-	return res, true, nil
+	return HgetInt64Ctx(context.Background(), key, field)
 }
 
+// Deprecated: 使用HgetAsCtx[int64]
+func HgetInt64Ctx(ctx context.Context, key *Key, field any) (int64, bool, error) {
+	return HgetAsCtx[int64](ctx, key, field)
+}
+
+// Deprecated: 使用HgetAs[float64]
 func HgetFloat64(key *Key, field any) (float64, bool, error) {
-	res, err := redis.Float64(DoCmdWithTTL(nil, "HGET", key, field))
-	if err != nil {
-		if errors.Is(err, redis.ErrNil) {
-			return 0, false, nil
-		}
-		return 0, false, err
-	}
-	return res, true, nil
+	return HgetFloat64Ctx(context.Background(), key, field)
+}
+
+// Deprecated: 使用HgetAsCtx[float64]
+func HgetFloat64Ctx(ctx context.Context, key *Key, field any) (float64, bool, error) {
+	return HgetAsCtx[float64](ctx, key, field)
 }
 
 func Hgetall(key *Key) (map[string]string, bool, error) {
+	return HgetallCtx(context.Background(), key)
+}
+
+func HgetallCtx(ctx context.Context, key *Key) (map[string]string, bool, error) {
 	m := make(map[string]string)
-	data, err := redis.Strings(DoCmdWithTTL(nil, "HGETALL", key))
+	data, err := redis.Strings(DoCmdWithTTLContext(ctx, nil, "HGETALL", key))
 	if err != nil {
 		if errors.Is(err, redis.ErrNil) {
 			return nil, false, nil
@@ -63,7 +69,11 @@ func Hgetall(key *Key) (map[string]string, bool, error) {
 }
 
 func HgetallStrings(key *Key) ([]string, bool, error) {
-	res, err := redis.Strings(DoCmdWithTTL(nil, "HGETALL", key))
+	return HgetallStringsCtx(context.Background(), key)
+}
+
+func HgetallStringsCtx(ctx context.Context, key *Key) ([]string, bool, error) {
+	res, err := redis.Strings(DoCmdWithTTLContext(ctx, nil, "HGETALL", key))
 	if err != nil {
 		if errors.Is(err, redis.ErrNil) {
 			return []string{}, false, nil
@@ -74,16 +84,16 @@ func HgetallStrings(key *Key) ([]string, bool, error) {
 }
 
 func Hsetnx(key *Key, field, data any, ttl int64) (bool, error) {
-	str, ok := data.(string)
-	if !ok { //不是字符串
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return false, err
-		}
+	return HsetnxCtx(context.Background(), key, field, data, ttl)
+}
+
+func HsetnxCtx(ctx context.Context, key *Key, field, data any, ttl int64) (bool, error) {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return false, err
 	}
 
-	res, err := redis.Int(DoCmdWithTTL(NewAsyncSecTTL(ttl), "HSETNX", key, field, str))
+	res, err := redis.Int(DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "HSETNX", key, field, str))
 	if err != nil {
 		return false, err
 	}
@@ -92,16 +102,16 @@ func Hsetnx(key *Key, field, data any, ttl int64) (bool, error) {
 }
 
 func Hset(key *Key, field, data any, ttl int64) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return HsetCtx(context.Background(), key, field, data, ttl)
+}
+
+func HsetCtx(ctx context.Context, key *Key, field, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	_, err := DoCmdWithTTL(NewAsyncSecTTL(ttl), "HSET", key, field, str)
+	_, err = DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "HSET", key, field, str)
 	if err != nil {
 		return err
 	}
@@ -110,15 +120,15 @@ func Hset(key *Key, field, data any, ttl int64) error {
 }
 
 func AsyncHset(key *Key, field, data any, ttl int64) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return AsyncHsetCtx(context.Background(), key, field, data, ttl)
+}
+
+func AsyncHsetCtx(ctx context.Context, key *Key, field, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
-	err := SendCmdWithTTL(NewAsyncSecTTL(ttl), "HSET", key, field, str)
+	err = SendCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "HSET", key, field, str)
 	if err != nil {
 		return nil
 	}
@@ -127,14 +137,22 @@ func AsyncHset(key *Key, field, data any, ttl int64) error {
 }
 
 func Hmset(ttl int64, key *Key, data ...any) error {
-	if _, err := DoCmdWithTTL(NewAsyncSecTTL(ttl), "HMSET", key, data...); err != nil {
+	return HmsetCtx(context.Background(), ttl, key, data...)
+}
+
+func HmsetCtx(ctx context.Context, ttl int64, key *Key, data ...any) error {
+	if _, err := DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "HMSET", key, data...); err != nil {
 		return err
 	}
 	return nil
 }
 
 func AsyncHmset(ttl int64, key *Key, data ...any) error {
-	err := SendCmdWithTTL(NewAsyncSecTTL(ttl), "HMSET", key, data...)
+	return AsyncHmsetCtx(context.Background(), ttl, key, data...)
+}
+
+func AsyncHmsetCtx(ctx context.Context, ttl int64, key *Key, data ...any) error {
+	err := SendCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "HMSET", key, data...)
 	if err != nil {
 		return err
 	}
@@ -143,7 +161,11 @@ func AsyncHmset(ttl int64, key *Key, data ...any) error {
 }
 
 func Hmget(key *Key, fields ...any) ([]int64, bool, error) {
-	res, err := redis.Int64s(DoCmdWithTTL(nil, "HMGET", key, fields...))
+	return HmgetCtx(context.Background(), key, fields...)
+}
+
+func HmgetCtx(ctx context.Context, key *Key, fields ...any) ([]int64, bool, error) {
+	res, err := redis.Int64s(DoCmdWithTTLContext(ctx, nil, "HMGET", key, fields...))
 	if err != nil {
 		if errors.Is(err, redis.ErrNil) {
 			return nil, false, nil
@@ -155,70 +177,71 @@ func Hmget(key *Key, fields ...any) ([]int64, bool, error) {
 }
 
 func Hmdel(key *Key, fields ...any) error {
-	_, err := DoCmdWithTTL(nil, "HDEL", key, fields...)
+	return HmdelCtx(context.Background(), key, fields...)
+}
+
+func HmdelCtx(ctx context.Context, key *Key, fields ...any) error {
+	_, err := DoCmdWithTTLContext(ctx, nil, "HDEL", key, fields...)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// Deprecated: 使用HgetallAs[uint64, int64]
 func HgetallMapUint64ToInt64(key *Key) (map[uint64]int64, bool, error) {
-	m := map[uint64]int64{}
+	return HgetallMapUint64ToInt64Ctx(context.Background(), key)
+}
 
-	data, err := redis.Int64s(DoCmdWithTTL(nil, "HGETALL", key))
+// 与HgetallAsCtx不同, 空hash仍返回true, 保持原有语义
+//
+// Deprecated: 使用HgetallAsCtx[uint64, int64]
+func HgetallMapUint64ToInt64Ctx(ctx context.Context, key *Key) (map[uint64]int64, bool, error) {
+	m, _, err := HgetallAsCtx[uint64, int64](ctx, key)
 	if err != nil {
-		if errors.Is(err, redis.ErrNil) {
-			return nil, false, nil
-		}
 		return nil, false, err
 	}
-
-	if len(data) != 0 {
-		return m, true, nil
-	}
-
-	dataLen := len(data)
-
-	for i := 0; i < dataLen; i += 2 {
-		k := uint64(data[i])
-		m[k] = data[i+1]
-	}
 	return m, true, nil
 }
 
+// Deprecated: 使用HgetallAs[int64, int64]
 func HgetallInt64Map(key *Key) (map[int64]int64, bool, error) {
-	m := map[int64]int64{}
+	return HgetallInt64MapCtx(context.Background(), key)
+}
 
-	data, err := redis.Int64s(DoCmdWithTTL(nil, "HGETALL", key))
+// 与HgetallAsCtx不同, 空hash仍返回true, 保持原有语义
+//
+// Deprecated: 使用HgetallAsCtx[int64, int64]
+func HgetallInt64MapCtx(ctx context.Context, key *Key) (map[int64]int64, bool, error) {
+	m, _, err := HgetallAsCtx[int64, int64](ctx, key)
 	if err != nil {
-		if errors.Is(err, redis.ErrNil) {
-			return nil, false, nil
-		}
 		return nil, false, err
 	}
-
-	if len(data)%2 != 0 {
-		return m, true, nil
-	}
-
-	dataLen := len(data)
-	for i := 0; i < dataLen; i += 2 {
-		k := data[i]
-		m[k] = data[i+1]
-	}
 	return m, true, nil
 }
 
 func Hincrby(key *Key, field any, inc int64, ttl int64) (int64, error) {
-	return redis.Int64(DoCmdWithTTL(NewAsyncSecTTL(ttl), "HINCRBY", key, field, inc))
+	return HincrbyCtx(context.Background(), key, field, inc, ttl)
+}
+
+func HincrbyCtx(ctx context.Context, key *Key, field any, inc int64, ttl int64) (int64, error) {
+	return redis.Int64(DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "HINCRBY", key, field, inc))
 }
 
 func AsyncHincrby(key *Key, field any, inc int64, ttl int64) error {
-	return SendCmdWithTTL(NewAsyncSecTTL(ttl), "HINCRBY", key, field, inc)
+	return AsyncHincrbyCtx(context.Background(), key, field, inc, ttl)
+}
+
+func AsyncHincrbyCtx(ctx context.Context, key *Key, field any, inc int64, ttl int64) error {
+	return SendCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "HINCRBY", key, field, inc)
 }
 
 func Hdel(key *Key, field any) error {
-	_, err := DoCmdWithTTL(nil, "HDEL", key, field)
+	return HdelCtx(context.Background(), key, field)
+}
+
+func HdelCtx(ctx context.Context, key *Key, field any) error {
+	_, err := DoCmdWithTTLContext(ctx, nil, "HDEL", key, field)
 	if err != nil {
 		return err
 	}
@@ -226,11 +249,19 @@ func Hdel(key *Key, field any) error {
 }
 
 func AsyncHdel(key *Key, field any) error {
-	return SendCmdWithTTL(nil, "HDEL", key, field)
+	return AsyncHdelCtx(context.Background(), key, field)
+}
+
+func AsyncHdelCtx(ctx context.Context, key *Key, field any) error {
+	return SendCmdWithTTLContext(ctx, nil, "HDEL", key, field)
 }
 
 func Hexists(key *Key, field any) (bool, error) {
-	res, err := redis.Bool(DoCmdWithTTL(nil, "HEXISTS", key, field))
+	return HexistsCtx(context.Background(), key, field)
+}
+
+func HexistsCtx(ctx context.Context, key *Key, field any) (bool, error) {
+	res, err := redis.Bool(DoCmdWithTTLContext(ctx, nil, "HEXISTS", key, field))
 	if err != nil {
 		return false, err
 	}
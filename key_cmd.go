@@ -1,11 +1,132 @@
 package routeredis
 
-import "github.com/gomodule/redigo/redis"
+import (
+	"context"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
 
 func Exists(key *Key) (bool, error) {
-	return redis.Bool(DoCmdWithTTL(nil, "EXISTS", key))
+	return ExistsCtx(context.Background(), key)
+}
+
+func ExistsCtx(ctx context.Context, key *Key) (bool, error) {
+	return redis.Bool(DoCmdWithTTLContext(ctx, nil, "EXISTS", key))
 }
 
 func Type(key *Key) (string, error) {
-	return redis.String(DoCmdWithTTL(nil, "TYPE", key))
+	return TypeCtx(context.Background(), key)
+}
+
+func TypeCtx(ctx context.Context, key *Key) (string, error) {
+	return redis.String(DoCmdWithTTLContext(ctx, nil, "TYPE", key))
+}
+
+// ScanKeys 通过SCAN遍历路由所在连接中匹配match的key, match自动加上路由前缀, 为空时匹配前缀下全部key,
+// 回调的Key已去掉前缀, fn返回错误时停止遍历, 集群模式下依次遍历全部主节点
+func ScanKeys(route string, match string, count int64, fn func(key *Key) error) error {
+	return ScanKeysCtx(context.Background(), route, match, count, fn)
+}
+
+func ScanKeysCtx(ctx context.Context, route string, match string, count int64, fn func(key *Key) error) error {
+	return defaultClient.ScanKeysCtx(ctx, route, match, count, fn)
+}
+
+func (c *Client) ScanKeys(route string, match string, count int64, fn func(key *Key) error) error {
+	return c.ScanKeysCtx(context.Background(), route, match, count, fn)
+}
+
+// ScanKeysCtx 回调的Key属于c
+func (c *Client) ScanKeysCtx(ctx context.Context, route string, match string, count int64, fn func(key *Key) error) error {
+	r, ok := c.lookupKeyRoute(route)
+	if !ok {
+		return ErrRedisKeyRouteNotRegistered
+	}
+	return c.scanRouteKeys(ctx, route, r, match, count, fn)
+}
+
+// scanRouteKeys 分片路由依次遍历每个分片连接
+func (c *Client) scanRouteKeys(ctx context.Context, route string, r *keyRoute, match string, count int64, fn func(key *Key) error) error {
+	if match == "" {
+		match = "*"
+	}
+
+	connNames := []string{r.connName}
+	if r.ring != nil {
+		connNames = r.ring.connNames
+	}
+
+	for _, connName := range connNames {
+		pool, err := c.GetConnPool(connName)
+		if err != nil {
+			return err
+		}
+
+		prefix := c.keyPrefix(r, connName)
+		newKey := func(key string) *Key {
+			return &Key{Route: route, Key: strings.TrimPrefix(key, prefix), c: c}
+		}
+		if cluster, ok := pool.(*RedisCluster); ok {
+			err = cluster.Cluster.EachNode(false, func(_ string, conn redis.Conn) error {
+				return scanKeys(ctx, conn, prefix+match, count, newKey, fn)
+			})
+		} else {
+			err = scanConnKeys(ctx, pool, prefix+match, count, newKey, fn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanConnKeys(ctx context.Context, pool RedisPool, match string, count int64, newKey func(key string) *Key, fn func(key *Key) error) error {
+	conn, err := getPoolConnContext(ctx, pool)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return scanKeys(ctx, conn, match, count, newKey, fn)
+}
+
+func scanKeys(ctx context.Context, conn redis.Conn, match string, count int64, newKey func(key string) *Key, fn func(key *Key) error) error {
+	args := []any{0, "MATCH", match}
+	if count > 0 {
+		args = append(args, "COUNT", count)
+	}
+
+	for {
+		res, err := redis.Values(doContext(ctx, conn, "SCAN", args...))
+		if err != nil {
+			return err
+		}
+
+		if len(res) != 2 {
+			return nil
+		}
+
+		cursor, err := redis.Int64(res[0], nil)
+		if err != nil {
+			return err
+		}
+
+		keys, err := redis.Strings(res[1], nil)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err = fn(newKey(key)); err != nil {
+				return err
+			}
+		}
+
+		if cursor == 0 {
+			return nil
+		}
+		args[0] = cursor
+	}
 }
@@ -0,0 +1,257 @@
+package routeredis
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrKeyTemplateInvalid       = errors.New("redis key template invalid")
+	ErrKeyTemplateDuplicated    = errors.New("redis key template already registered")
+	ErrKeyTemplateParamMismatch = errors.New("redis key template params mismatch")
+)
+
+type KeyTemplateConf struct {
+	Route string
+	// Pattern 占位符格式为{name}, name只能包含字母, 数字和下划线且不能以数字开头, 例如"user:{uid}:profile",
+	// {{和}}输出字面量的{和}, 用于集群hashtag, 例如"cart:{{{uid}}}:items"生成"cart:{1}:items"
+	Pattern string
+	// ValueType和Desc只作为模板的元数据供调用方读取(例如生成文档), 过期时间通过路由的KeyRouteOpts.TTL配置
+	ValueType reflect.Type
+	Desc      string
+}
+
+// KeyTemplate 统一声明key的路由和格式, 注册时校验占位符, 按占位符顺序或者名称生成Key.
+// 模板通常先于路由注册(例如包级变量), 注册时不检查路由, 路由配置完成后调用ValidateKeyTemplates检查
+type KeyTemplate struct {
+	route     string
+	pattern   string
+	valueType reflect.Type
+	desc      string
+	segments  []string // 字面量, 与params交替拼接, 长度为len(params)+1
+	params    []string
+	c         *Client
+}
+
+func RegisterKeyTemplate(conf *KeyTemplateConf) (*KeyTemplate, error) {
+	return defaultClient.RegisterKeyTemplate(conf)
+}
+
+// RegisterKeyTemplate 在c中注册模板, 模板生成的key属于c
+func (c *Client) RegisterKeyTemplate(conf *KeyTemplateConf) (*KeyTemplate, error) {
+	if conf.Route == "" {
+		return nil, fmt.Errorf("%w: route is empty", ErrKeyTemplateInvalid)
+	}
+
+	segments, params, err := parseKeyPattern(conf.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &KeyTemplate{
+		route:     conf.Route,
+		pattern:   conf.Pattern,
+		valueType: conf.ValueType,
+		desc:      conf.Desc,
+		segments:  segments,
+		params:    params,
+		c:         c,
+	}
+	if _, loaded := c.keyTemplates.LoadOrStore(keyTemplateId(conf.Route, conf.Pattern), tmpl); loaded {
+		return nil, fmt.Errorf("%w: %s %s", ErrKeyTemplateDuplicated, conf.Route, conf.Pattern)
+	}
+
+	return tmpl, nil
+}
+
+func MustRegisterKeyTemplate(conf *KeyTemplateConf) *KeyTemplate {
+	return defaultClient.MustRegisterKeyTemplate(conf)
+}
+
+// MustRegisterKeyTemplate 用于包级变量声明, 模板非法时panic
+func (c *Client) MustRegisterKeyTemplate(conf *KeyTemplateConf) *KeyTemplate {
+	tmpl, err := c.RegisterKeyTemplate(conf)
+	if err != nil {
+		panic(err)
+	}
+	return tmpl
+}
+
+func KeyTemplates() []*KeyTemplate {
+	return defaultClient.KeyTemplates()
+}
+
+// KeyTemplates 返回c中全部已注册的模板, 按路由和格式排序
+func (c *Client) KeyTemplates() []*KeyTemplate {
+	var tmpls []*KeyTemplate
+	c.keyTemplates.Range(func(_, v any) bool {
+		tmpls = append(tmpls, v.(*KeyTemplate))
+		return true
+	})
+	sort.Slice(tmpls, func(i, j int) bool {
+		if tmpls[i].route != tmpls[j].route {
+			return tmpls[i].route < tmpls[j].route
+		}
+		return tmpls[i].pattern < tmpls[j].pattern
+	})
+	return tmpls
+}
+
+func ValidateKeyTemplates() error {
+	return defaultClient.ValidateKeyTemplates()
+}
+
+// ValidateKeyTemplates 检查c中全部模板的路由都能解析(精确, 分片, 通配或者兜底路由), 用于启动时在路由配置完成后调用
+func (c *Client) ValidateKeyTemplates() error {
+	var errs []error
+	for _, tmpl := range c.KeyTemplates() {
+		if err := tmpl.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func keyTemplateId(route, pattern string) string {
+	return route + "\x00" + pattern
+}
+
+func parseKeyPattern(pattern string) ([]string, []string, error) {
+	var (
+		segments []string
+		params   []string
+		seen     = map[string]bool{}
+		literal  strings.Builder
+	)
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "{{"), strings.HasPrefix(pattern[i:], "}}"):
+			literal.WriteByte(pattern[i])
+			i++
+		case pattern[i] == '}':
+			return nil, nil, fmt.Errorf("%w: unexpected '}' in %q", ErrKeyTemplateInvalid, pattern)
+		case pattern[i] == '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, nil, fmt.Errorf("%w: unclosed '{' in %q", ErrKeyTemplateInvalid, pattern)
+			}
+			name := pattern[i+1 : i+end]
+			if !isKeyParamName(name) {
+				return nil, nil, fmt.Errorf("%w: bad placeholder {%s} in %q", ErrKeyTemplateInvalid, name, pattern)
+			}
+			if seen[name] {
+				return nil, nil, fmt.Errorf("%w: duplicated placeholder {%s} in %q", ErrKeyTemplateInvalid, name, pattern)
+			}
+			seen[name] = true
+			segments = append(segments, literal.String())
+			literal.Reset()
+			params = append(params, name)
+			i += end
+		default:
+			literal.WriteByte(pattern[i])
+		}
+	}
+	segments = append(segments, literal.String())
+
+	if len(params) == 0 && segments[0] == "" {
+		return nil, nil, fmt.Errorf("%w: pattern is empty", ErrKeyTemplateInvalid)
+	}
+
+	return segments, params, nil
+}
+
+func isKeyParamName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (t *KeyTemplate) Route() string {
+	return t.route
+}
+
+func (t *KeyTemplate) Pattern() string {
+	return t.pattern
+}
+
+func (t *KeyTemplate) ValueType() reflect.Type {
+	return t.valueType
+}
+
+func (t *KeyTemplate) Desc() string {
+	return t.desc
+}
+
+// Validate 检查模板的路由在所属Client中能够解析
+func (t *KeyTemplate) Validate() error {
+	if _, ok := t.c.lookupKeyRoute(t.route); !ok {
+		return fmt.Errorf("%w: template %s route %s", ErrRedisKeyRouteNotRegistered, t.pattern, t.route)
+	}
+	return nil
+}
+
+// Params 占位符名称, 按在格式中出现的顺序
+func (t *KeyTemplate) Params() []string {
+	return append([]string(nil), t.params...)
+}
+
+// Key 按占位符出现的顺序传入参数
+func (t *KeyTemplate) Key(args ...any) (*Key, error) {
+	if len(args) != len(t.params) {
+		return nil, fmt.Errorf("%w: %s expects %d params, got %d", ErrKeyTemplateParamMismatch, t.pattern, len(t.params), len(args))
+	}
+	return t.build(args), nil
+}
+
+func (t *KeyTemplate) MustKey(args ...any) *Key {
+	key, err := t.Key(args...)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// KeyWith 按占位符名称传入参数, 缺少或者多出参数都会报错
+func (t *KeyTemplate) KeyWith(params map[string]any) (*Key, error) {
+	if len(params) != len(t.params) {
+		return nil, fmt.Errorf("%w: %s expects params %v", ErrKeyTemplateParamMismatch, t.pattern, t.params)
+	}
+
+	args := make([]any, 0, len(t.params))
+	for _, name := range t.params {
+		arg, ok := params[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s missing param %s", ErrKeyTemplateParamMismatch, t.pattern, name)
+		}
+		args = append(args, arg)
+	}
+
+	return t.build(args), nil
+}
+
+func (t *KeyTemplate) build(args []any) *Key {
+	var b strings.Builder
+	for i, arg := range args {
+		b.WriteString(t.segments[i])
+		fmt.Fprint(&b, arg)
+	}
+	b.WriteString(t.segments[len(t.segments)-1])
+
+	return &Key{
+		Route: t.route,
+		Key:   b.String(),
+		c:     t.c,
+	}
+}
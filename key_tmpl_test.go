@@ -0,0 +1,76 @@
+package routeredis
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestKeyTemplate(t *testing.T) {
+	for _, pattern := range []string{"", "user:{uid", "user:}", "user:{}", "user:{1uid}", "user:{uid}:{uid}"} {
+		if _, err := RegisterKeyTemplate(&KeyTemplateConf{Route: "tmpl", Pattern: pattern}); !errors.Is(err, ErrKeyTemplateInvalid) {
+			t.Fatalf("expect invalid pattern %q, got %v", pattern, err)
+		}
+	}
+
+	tmpl := MustRegisterKeyTemplate(&KeyTemplateConf{
+		Route:     "tmpl",
+		Pattern:   "user:{uid}:item:{item_id}",
+		ValueType: reflect.TypeOf(""),
+	})
+	if _, err := RegisterKeyTemplate(&KeyTemplateConf{Route: "tmpl", Pattern: "user:{uid}:item:{item_id}"}); !errors.Is(err, ErrKeyTemplateDuplicated) {
+		t.Fatalf("expect duplicated, got %v", err)
+	}
+
+	key := tmpl.MustKey(1, "a")
+	if key.Route != "tmpl" || key.Key != "user:1:item:a" {
+		t.Fatalf("unexpected key %+v", key)
+	}
+
+	key, err := tmpl.KeyWith(map[string]any{"item_id": "b", "uid": 2})
+	if err != nil || key.Key != "user:2:item:b" {
+		t.Fatalf("unexpected key %+v %v", key, err)
+	}
+
+	if _, err = tmpl.Key(1); !errors.Is(err, ErrKeyTemplateParamMismatch) {
+		t.Fatalf("expect param mismatch, got %v", err)
+	}
+	if _, err = tmpl.KeyWith(map[string]any{"uid": 1, "item": 2}); !errors.Is(err, ErrKeyTemplateParamMismatch) {
+		t.Fatalf("expect param mismatch, got %v", err)
+	}
+
+	// {{和}}转义为字面量, 用于生成集群hashtag
+	hashTag := MustRegisterKeyTemplate(&KeyTemplateConf{Route: "tmpl", Pattern: "cart:{{{uid}}}:items:{{v2}}"})
+	if params := hashTag.Params(); len(params) != 1 || params[0] != "uid" {
+		t.Fatalf("unexpected params %v", params)
+	}
+	if key = hashTag.MustKey(7); key.Key != "cart:{7}:items:{v2}" {
+		t.Fatalf("unexpected hashtag key %+v", key)
+	}
+
+	// 路由注册前模板无法通过校验
+	c := NewClient()
+	routed := c.MustRegisterKeyTemplate(&KeyTemplateConf{Route: "tmpl", Pattern: "user:{uid}"})
+	if err = c.ValidateKeyTemplates(); !errors.Is(err, ErrRedisKeyRouteNotRegistered) {
+		t.Fatalf("expect route not registered, got %v", err)
+	}
+	if err = c.RegisterKeyRoutePattern("tm*", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err = routed.Validate(); err != nil {
+		t.Fatalf("expect pattern route resolved, got %v", err)
+	}
+	if err = c.ValidateKeyTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, item := range KeyTemplates() {
+		if item == tmpl {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expect template listed")
+	}
+}
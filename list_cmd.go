@@ -1,12 +1,16 @@
 package routeredis
 
 import (
+	"context"
 	"github.com/gomodule/redigo/redis"
-	jsoniter "github.com/json-iterator/go"
 )
 
 func Rpop(key *Key) (string, error) {
-	res, err := redis.Strings(DoCmdWithTTL(nil, "BRPOP", key, 1))
+	return RpopCtx(context.Background(), key)
+}
+
+func RpopCtx(ctx context.Context, key *Key) (string, error) {
+	res, err := redis.Strings(DoCmdWithTTLContext(ctx, nil, "BRPOP", key, 1))
 	if err != nil {
 		return "", err
 	}
@@ -17,20 +21,24 @@ func Rpop(key *Key) (string, error) {
 }
 
 func Llen(key *Key) (int64, error) {
-	return redis.Int64(DoCmdWithTTL(nil, "LLEN", key))
+	return LlenCtx(context.Background(), key)
+}
+
+func LlenCtx(ctx context.Context, key *Key) (int64, error) {
+	return redis.Int64(DoCmdWithTTLContext(ctx, nil, "LLEN", key))
 }
 
 func AsyncLpush(key *Key, data any, ttl int64) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return AsyncLpushCtx(context.Background(), key, data, ttl)
+}
+
+func AsyncLpushCtx(ctx context.Context, key *Key, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	err := SendCmdWithTTL(NewAsyncSecTTL(ttl), "LPUSH", key, str)
+	err = SendCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "LPUSH", key, str)
 	if err != nil {
 		return err
 	}
@@ -39,16 +47,16 @@ func AsyncLpush(key *Key, data any, ttl int64) error {
 }
 
 func Lpush(key *Key, data any, ttl int64) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return LpushCtx(context.Background(), key, data, ttl)
+}
+
+func LpushCtx(ctx context.Context, key *Key, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	_, err := DoCmdWithTTL(NewAsyncSecTTL(ttl), "LPUSH", key, str)
+	_, err = DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "LPUSH", key, str)
 	if err != nil {
 		return err
 	}
@@ -57,16 +65,16 @@ func Lpush(key *Key, data any, ttl int64) error {
 }
 
 func Rpush(key *Key, data any, ttl int64) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return RpushCtx(context.Background(), key, data, ttl)
+}
+
+func RpushCtx(ctx context.Context, key *Key, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	_, err := DoCmdWithTTL(NewAsyncSecTTL(ttl), "RPUSH", key, str)
+	_, err = DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "RPUSH", key, str)
 	if err != nil {
 		return err
 	}
@@ -75,16 +83,16 @@ func Rpush(key *Key, data any, ttl int64) error {
 }
 
 func AsyncRpush(key *Key, data any, ttl int64) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return AsyncRpushCtx(context.Background(), key, data, ttl)
+}
+
+func AsyncRpushCtx(ctx context.Context, key *Key, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	err := SendCmdWithTTL(NewAsyncSecTTL(ttl), "RPUSH", key, str)
+	err = SendCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "RPUSH", key, str)
 	if err != nil {
 		return err
 	}
@@ -93,7 +101,11 @@ func AsyncRpush(key *Key, data any, ttl int64) error {
 }
 
 func Lpop(key *Key) (string, error) {
-	res, err := redis.Strings(DoCmdWithTTL(nil, "BLPOP", key, 1))
+	return LpopCtx(context.Background(), key)
+}
+
+func LpopCtx(ctx context.Context, key *Key) (string, error) {
+	res, err := redis.Strings(DoCmdWithTTLContext(ctx, nil, "BLPOP", key, 1))
 	if err != nil {
 		return "", err
 	}
@@ -106,16 +118,16 @@ func Lpop(key *Key) (string, error) {
 }
 
 func AsyncLrem(key *Key, data any) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return AsyncLremCtx(context.Background(), key, data)
+}
+
+func AsyncLremCtx(ctx context.Context, key *Key, data any) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	err := SendCmdWithTTL(nil, "LREM", key, 0, str)
+	err = SendCmdWithTTLContext(ctx, nil, "LREM", key, 0, str)
 	if err != nil {
 		return err
 	}
@@ -124,15 +136,29 @@ func AsyncLrem(key *Key, data any) error {
 }
 
 func Lrange(key *Key, start, end int32) ([]string, error) {
-	return redis.Strings(DoCmdWithTTL(nil, "LRANGE", key, start, end))
+	return LrangeCtx(context.Background(), key, start, end)
+}
+
+func LrangeCtx(ctx context.Context, key *Key, start, end int32) ([]string, error) {
+	return redis.Strings(DoCmdWithTTLContext(ctx, nil, "LRANGE", key, start, end))
 }
 
+// Deprecated: 使用LrangeAs[int64]
 func LrangeInt64(key *Key, start, end int32) ([]int64, error) {
-	return redis.Int64s(DoCmdWithTTL(nil, "LRANGE", key, start, end))
+	return LrangeInt64Ctx(context.Background(), key, start, end)
+}
+
+// Deprecated: 使用LrangeAsCtx[int64]
+func LrangeInt64Ctx(ctx context.Context, key *Key, start, end int32) ([]int64, error) {
+	return LrangeAsCtx[int64](ctx, key, start, end)
 }
 
 func Ltrim(key *Key, start, end int32) error {
-	_, err := DoCmdWithTTL(nil, "LTRIM", key, start, end)
+	return LtrimCtx(context.Background(), key, start, end)
+}
+
+func LtrimCtx(ctx context.Context, key *Key, start, end int32) error {
+	_, err := DoCmdWithTTLContext(ctx, nil, "LTRIM", key, start, end)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,246 @@
+package routeredis
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	ErrLockNotHeld           = errors.New("redis lock not held")
+	ErrLockRenewIntervalZero = errors.New("redis lock renew interval must be positive")
+)
+
+const (
+	DefaultLockMinBackoff = 10 * time.Millisecond
+	DefaultLockMaxBackoff = 500 * time.Millisecond
+)
+
+var (
+	lockReleaseScript = RegisterScript("routeredis:lock:release", `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	lockExtendScript = RegisterScript("routeredis:lock:extend", `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Lock 基于路由key的分布式锁, 通过SET NX PX加锁并写入随机token,
+// 释放和续期时校验token, 避免误删其他持有者的锁
+type Lock struct {
+	key        *Key
+	ttl        time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	mu         sync.Mutex
+	token      string
+	stopRenew  context.CancelFunc
+	renewDone  chan struct{}
+}
+
+func NewLock(key *Key, ttl time.Duration) *Lock {
+	return &Lock{
+		key:        key,
+		ttl:        ttl,
+		minBackoff: DefaultLockMinBackoff,
+		maxBackoff: DefaultLockMaxBackoff,
+	}
+}
+
+func (k *Key) Lock(ttl time.Duration) *Lock {
+	return NewLock(k, ttl)
+}
+
+// SetBackoff 设置Acquire重试的退避区间, 每次失败后等待时间翻倍并加入随机抖动
+func (l *Lock) SetBackoff(min, max time.Duration) *Lock {
+	l.minBackoff, l.maxBackoff = min, max
+	return l
+}
+
+func (l *Lock) Key() *Key {
+	return l.key
+}
+
+func (l *Lock) Token() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.token
+}
+
+func (l *Lock) TryAcquire(ctx context.Context) (bool, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := DoCmdWithTTLContext(ctx, nil, "SET", l.key, token, "NX", "PX", l.ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+
+	if res == nil {
+		return false, nil
+	}
+
+	l.mu.Lock()
+	l.token = token
+	l.mu.Unlock()
+
+	return true, nil
+}
+
+// Acquire 阻塞直到加锁成功或者ctx结束
+func (l *Lock) Acquire(ctx context.Context) error {
+	backoff := l.minBackoff
+	if backoff <= 0 {
+		backoff = DefaultLockMinBackoff
+	}
+	for {
+		ok, err := l.TryAcquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+
+		wait := backoff/2 + rand.N(backoff/2+1)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > l.maxBackoff {
+			backoff = l.maxBackoff
+		}
+	}
+}
+
+func (l *Lock) Release(ctx context.Context) error {
+	l.StopAutoRenew()
+
+	token := l.Token()
+	if token == "" {
+		return ErrLockNotHeld
+	}
+
+	res, err := redis.Int(lockReleaseScript.DoContext(ctx, []*Key{l.key}, token))
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.token = ""
+	l.mu.Unlock()
+
+	if res == 0 {
+		return ErrLockNotHeld
+	}
+
+	return nil
+}
+
+// Extend 将锁的过期时间重置为ttl, 锁已过期或者被他人持有时返回ErrLockNotHeld
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	token := l.Token()
+	if token == "" {
+		return ErrLockNotHeld
+	}
+
+	res, err := redis.Int(lockExtendScript.DoContext(ctx, []*Key{l.key}, token, ttl.Milliseconds()))
+	if err != nil {
+		return err
+	}
+
+	if res == 0 {
+		return ErrLockNotHeld
+	}
+
+	return nil
+}
+
+// StartAutoRenew 后台每隔interval将锁续期为初始ttl, interval<=0时取ttl/3, 取值后仍<=0时返回ErrLockRenewIntervalZero,
+// 续期失败时回调onErr, 锁丢失(ErrLockNotHeld)后停止续期
+func (l *Lock) StartAutoRenew(interval time.Duration, onErr func(err error)) error {
+	if interval <= 0 {
+		interval = l.ttl / 3
+	}
+	if interval <= 0 {
+		return ErrLockRenewIntervalZero
+	}
+
+	l.StopAutoRenew()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	l.mu.Lock()
+	l.stopRenew, l.renewDone = cancel, done
+	l.mu.Unlock()
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := l.Extend(ctx, l.ttl)
+			if err == nil || ctx.Err() != nil {
+				continue
+			}
+
+			if onErr != nil {
+				onErr(err)
+			}
+
+			if errors.Is(err, ErrLockNotHeld) {
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (l *Lock) StopAutoRenew() {
+	l.mu.Lock()
+	stop, done := l.stopRenew, l.renewDone
+	l.stopRenew, l.renewDone = nil, nil
+	l.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	stop()
+	<-done
+}
+
+func newLockToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := cryptorand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
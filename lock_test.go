@@ -0,0 +1,81 @@
+package routeredis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockAcquireRelease(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		store = map[string]string{}
+	)
+	addr := newFakeRedis(t, func(args []string) any {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch args[0] {
+		case "SET":
+			if _, ok := store[args[1]]; ok {
+				return nil
+			}
+			store[args[1]] = args[2]
+			return "OK"
+		case "SCRIPT":
+			return "sha"
+		case "EVALSHA":
+			key, token := args[3], args[4]
+			if store[key] != token {
+				return 0
+			}
+			if args[1] == lockReleaseScript.Hash() {
+				delete(store, key)
+			}
+			return 1
+		}
+		return nil
+	})
+
+	const route = "lock"
+	ConnectByConf(route, &ConnConf{Servers: []string{addr}})
+	RegisterKeyRoute(route, route)
+	key := NewKey(route, "job:%d", 1)
+
+	holder := key.Lock(time.Second)
+	if err := holder.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	other := key.Lock(time.Second).SetBackoff(time.Millisecond, 5*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := other.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect deadline exceeded while lock held, got %v", err)
+	}
+
+	if err := other.Release(context.Background()); err != ErrLockNotHeld {
+		t.Errorf("expect not held for other, got %v", err)
+	}
+
+	if err := holder.Extend(context.Background(), time.Second); err != nil {
+		t.Errorf("expect extend by holder, got %v", err)
+	}
+
+	if err := holder.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := other.TryAcquire(context.Background()); err != nil || !ok {
+		t.Errorf("expect acquire after release, got %v %v", ok, err)
+	}
+}
+
+func TestLockAutoRenewInterval(t *testing.T) {
+	for _, ttl := range []time.Duration{0, 2} {
+		if err := NewLock(NewKey("lock", "renew"), ttl).StartAutoRenew(0, nil); err != ErrLockRenewIntervalZero {
+			t.Fatalf("ttl %v: expect ErrLockRenewIntervalZero, got %v", ttl, err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package metrics 将routeredis的命令耗时, 错误数, 执行中命令数以及连接池统计导出为prometheus指标
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/995933447/routeredis"
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultNamespace         = "routeredis"
+	DefaultPoolStatsInterval = 15 * time.Second
+)
+
+type Opts struct {
+	Namespace         string            // 为空时取DefaultNamespace
+	ConstLabels       prometheus.Labels // 附加在全部指标上, 例如区分多个Client
+	Buckets           []float64         // 命令耗时的直方图分桶(秒), 为空时取prometheus.DefBuckets
+	PoolStatsInterval time.Duration     // Run采集连接池统计的间隔, <=0时取DefaultPoolStatsInterval
+}
+
+// Metrics 实现了prometheus.Collector, 需要注册到Registerer并把Middleware加入Client,
+// 连接池统计由Run定期采集
+type Metrics struct {
+	client            *routeredis.Client
+	poolStatsInterval time.Duration
+
+	cmdDuration  *prometheus.HistogramVec
+	cmdErrors    *prometheus.CounterVec
+	cmdInFlight  *prometheus.GaugeVec
+	poolActive   *prometheus.GaugeVec
+	poolIdle     *prometheus.GaugeVec
+	poolWait     *prometheus.GaugeVec
+	poolWaitTime *prometheus.GaugeVec
+}
+
+var _ prometheus.Collector = (*Metrics)(nil)
+
+var (
+	cmdLabels  = []string{"cmd", "route", "conn"}
+	poolLabels = []string{"conn", "node"}
+)
+
+// New client为nil时使用routeredis.DefaultClient
+func New(client *routeredis.Client, opts *Opts) *Metrics {
+	if client == nil {
+		client = routeredis.DefaultClient()
+	}
+	if opts == nil {
+		opts = &Opts{}
+	}
+
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	buckets := opts.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	interval := opts.PoolStatsInterval
+	if interval <= 0 {
+		interval = DefaultPoolStatsInterval
+	}
+
+	gauge := func(name, help string, labels []string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        name,
+			Help:        help,
+			ConstLabels: opts.ConstLabels,
+		}, labels)
+	}
+
+	return &Metrics{
+		client:            client,
+		poolStatsInterval: interval,
+		cmdDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "cmd_duration_seconds",
+			Help:        "Latency of routed redis commands.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     buckets,
+		}, cmdLabels),
+		cmdErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "cmd_errors_total",
+			Help:        "Routed redis commands that returned an error.",
+			ConstLabels: opts.ConstLabels,
+		}, append(cmdLabels, "kind")),
+		cmdInFlight:  gauge("cmd_in_flight", "Routed redis commands currently executing.", cmdLabels),
+		poolActive:   gauge("pool_active_conns", "Connections in the pool, both in use and idle.", poolLabels),
+		poolIdle:     gauge("pool_idle_conns", "Idle connections in the pool.", poolLabels),
+		poolWait:     gauge("pool_wait_count", "Total number of times a connection was waited for.", poolLabels),
+		poolWaitTime: gauge("pool_wait_seconds", "Total time spent waiting for a connection.", poolLabels),
+	}
+}
+
+// Register 创建Metrics, 注册到reg并把Middleware加入client, 之后仍需调用Run采集连接池统计
+func Register(client *routeredis.Client, reg prometheus.Registerer, opts *Opts) (*Metrics, error) {
+	m := New(client, opts)
+	if err := reg.Register(m); err != nil {
+		return nil, err
+	}
+	m.client.Use(m.Middleware())
+	return m, nil
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.cmdDuration, m.cmdErrors, m.cmdInFlight,
+		m.poolActive, m.poolIdle, m.poolWait, m.poolWaitTime,
+	}
+}
+
+// Middleware 统计经过DoCmdWithTTL和SendCmdWithTTL的命令, 应放在其他中间件外层以包含重试的耗时
+func (m *Metrics) Middleware() routeredis.CmdMiddleware {
+	return func(next routeredis.CmdHandler) routeredis.CmdHandler {
+		return func(ctx context.Context, cmd *routeredis.Cmd) (any, error) {
+			connName, _ := routeredis.KeyConnName(cmd.Key)
+			labels := []string{cmd.Name, cmd.Key.Route, connName}
+
+			inFlight := m.cmdInFlight.WithLabelValues(labels...)
+			inFlight.Inc()
+			start := time.Now()
+
+			res, err := next(ctx, cmd)
+
+			inFlight.Dec()
+			m.observe(labels, time.Since(start), err)
+
+			return res, err
+		}
+	}
+}
+
+// OnCmdDone 用于统计不经过中间件的管道, 事务, 脚本和stream命令, 可以设置为Client的OnCmdDone,
+// DoCmdWithTTL和SendCmdWithTTL由Middleware统计, 这里会跳过避免重复
+func (m *Metrics) OnCmdDone(execCmdWay string, _ *routeredis.TTL, cost time.Duration, cmd string, key *routeredis.Key, err error, _ ...any) {
+	if execCmdWay == routeredis.CmdWayDo || execCmdWay == routeredis.CmdWaySend {
+		return
+	}
+	connName, _ := routeredis.KeyConnName(key)
+	m.observe([]string{cmd, key.Route, connName}, cost, err)
+}
+
+func (m *Metrics) observe(labels []string, cost time.Duration, err error) {
+	m.cmdDuration.WithLabelValues(labels...).Observe(cost.Seconds())
+	if err != nil {
+		m.cmdErrors.WithLabelValues(append(labels, errKind(err))...).Inc()
+	}
+}
+
+// errKind redis为服务端返回的错误, timeout为超时或者ctx取消, other为连接等其他错误
+func errKind(err error) string {
+	var redisErr redis.Error
+	switch {
+	case errors.As(err, &redisErr):
+		return "redis"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "timeout"
+	}
+	var netErr interface{ Timeout() bool }
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	return "other"
+}
+
+// Run 每隔PoolStatsInterval采集一次连接池统计, 直到ctx结束
+func (m *Metrics) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.poolStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		m.CollectPoolStats()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// CollectPoolStats 立即采集一次连接池统计, 已注销的连接和节点不再导出
+func (m *Metrics) CollectPoolStats() {
+	vecs := []*prometheus.GaugeVec{m.poolActive, m.poolIdle, m.poolWait, m.poolWaitTime}
+	for _, vec := range vecs {
+		vec.Reset()
+	}
+
+	for connName, nodes := range m.client.ConnPoolStats() {
+		for node, stats := range nodes {
+			m.poolActive.WithLabelValues(connName, node).Set(float64(stats.ActiveCount))
+			m.poolIdle.WithLabelValues(connName, node).Set(float64(stats.IdleCount))
+			m.poolWait.WithLabelValues(connName, node).Set(float64(stats.WaitCount))
+			m.poolWaitTime.WithLabelValues(connName, node).Set(stats.WaitDuration.Seconds())
+		}
+	}
+}
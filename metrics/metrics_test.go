@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/995933447/routeredis"
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// stubConn GET返回值, 其余命令返回服务端错误
+type stubConn struct{}
+
+func (stubConn) Close() error { return nil }
+func (stubConn) Err() error   { return nil }
+func (stubConn) Do(cmd string, args ...any) (any, error) {
+	if cmd == "GET" {
+		return []byte("v"), nil
+	}
+	return nil, redis.Error("ERR unknown command")
+}
+func (c stubConn) DoContext(_ context.Context, cmd string, args ...any) (any, error) {
+	return c.Do(cmd, args...)
+}
+func (stubConn) Send(string, ...any) error                   { return nil }
+func (stubConn) Flush() error                                { return nil }
+func (stubConn) Receive() (any, error)                       { return nil, nil }
+func (stubConn) ReceiveContext(context.Context) (any, error) { return nil, nil }
+
+func TestMetrics(t *testing.T) {
+	client := routeredis.NewClient()
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return stubConn{}, nil }}
+	client.Connect("main", pool)
+	client.RegisterKeyRoute("user", "main")
+
+	reg := prometheus.NewRegistry()
+	m, err := Register(client, reg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := client.NewKey("user", "1")
+	if _, err = routeredis.DoCmdWithTTL(nil, "GET", key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = routeredis.DoCmdWithTTL(nil, "BAD", key); err == nil {
+		t.Fatal("expect error")
+	}
+
+	if n := testutil.CollectAndCount(m, "routeredis_cmd_duration_seconds"); n != 2 {
+		t.Fatalf("expect 2 histogram series, got %d", n)
+	}
+	if v := testutil.ToFloat64(m.cmdErrors.WithLabelValues("BAD", "user", "main", "redis")); v != 1 {
+		t.Fatalf("expect 1 error, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.cmdInFlight.WithLabelValues("GET", "user", "main")); v != 0 {
+		t.Fatalf("expect no in flight, got %v", v)
+	}
+
+	conn := pool.Get()
+	m.CollectPoolStats()
+	if v := testutil.ToFloat64(m.poolActive.WithLabelValues("main", "")); v != 1 {
+		t.Fatalf("expect 1 active conn, got %v", v)
+	}
+	conn.Close()
+
+	if err = client.Disconnect("main"); err != nil {
+		t.Fatal(err)
+	}
+	m.CollectPoolStats()
+	if n := testutil.CollectAndCount(m, "routeredis_pool_active_conns"); n != 0 {
+		t.Fatalf("expect disconnected pool removed, got %d series", n)
+	}
+}
@@ -0,0 +1,102 @@
+package routeredis
+
+import (
+	"context"
+	"slices"
+)
+
+const (
+	CmdWayDo   = "DoCmdWithTTL"
+	CmdWaySend = "SendCmdWithTTL"
+)
+
+// Cmd 经过中间件链的一条命令, 中间件可以在调用next之前修改其中的字段
+type Cmd struct {
+	Way  string // CmdWayDo或者CmdWaySend, Send方式的应答始终为nil
+	TTL  *TTL
+	Name string
+	Key  *Key
+	Args []any
+}
+
+type CmdHandler func(ctx context.Context, cmd *Cmd) (any, error)
+
+// CmdMiddleware 与http.Handler的包装方式相同, 可以检查或修改cmd后调用next, 不调用next直接返回(短路),
+// 多次调用next(重试), 或者包装next返回的错误
+type CmdMiddleware func(next CmdHandler) CmdHandler
+
+func Use(middlewares ...CmdMiddleware) {
+	defaultClient.Use(middlewares...)
+}
+
+func UseRoute(route string, middlewares ...CmdMiddleware) {
+	defaultClient.UseRoute(route, middlewares...)
+}
+
+// Use 追加作用于c全部命令的中间件, 先注册的在外层, 全部位于路由中间件的外层
+func (c *Client) Use(middlewares ...CmdMiddleware) {
+	c.middlewareMu.Lock()
+	defer c.middlewareMu.Unlock()
+
+	current, _ := c.middlewares.Load().([]CmdMiddleware)
+	c.middlewares.Store(append(slices.Clip(current), middlewares...))
+}
+
+// UseRoute 追加只作用于route(Key.Route)下命令的中间件, 先注册的在外层
+func (c *Client) UseRoute(route string, middlewares ...CmdMiddleware) {
+	c.middlewareMu.Lock()
+	defer c.middlewareMu.Unlock()
+
+	var current []CmdMiddleware
+	if v, ok := c.routeMiddlewares.Load(route); ok {
+		current = v.([]CmdMiddleware)
+	}
+	c.routeMiddlewares.Store(route, append(slices.Clip(current), middlewares...))
+}
+
+// PipelineBatch 管道中发往同一连接(集群模式下同一节点)的一批命令, 执行结果写在各PipelineCmd中
+type PipelineBatch struct {
+	ConnName string
+	Node     string // 集群模式下的节点地址
+	Cmds     []*PipelineCmd
+}
+
+type PipelineHandler func(ctx context.Context, batch *PipelineBatch)
+
+// PipelineMiddleware 包装管道中每一批命令的执行, 不能增删batch.Cmds
+type PipelineMiddleware func(next PipelineHandler) PipelineHandler
+
+func UsePipeline(middlewares ...PipelineMiddleware) {
+	defaultClient.UsePipeline(middlewares...)
+}
+
+// UsePipeline 追加作用于c的管道中间件, 先注册的在外层, 管道的一批命令可能属于多个路由, 没有路由中间件
+func (c *Client) UsePipeline(middlewares ...PipelineMiddleware) {
+	c.middlewareMu.Lock()
+	defer c.middlewareMu.Unlock()
+
+	current, _ := c.pipelineMiddlewares.Load().([]PipelineMiddleware)
+	c.pipelineMiddlewares.Store(append(slices.Clip(current), middlewares...))
+}
+
+func (c *Client) pipelineHandler(handler PipelineHandler) PipelineHandler {
+	middlewares, _ := c.pipelineMiddlewares.Load().([]PipelineMiddleware)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// cmdHandler 按Client中间件, 路由中间件的顺序由外向内包装handler
+func (c *Client) cmdHandler(route string, handler CmdHandler) CmdHandler {
+	middlewares, _ := c.middlewares.Load().([]CmdMiddleware)
+	if v, ok := c.routeMiddlewares.Load(route); ok {
+		middlewares = append(slices.Clip(middlewares), v.([]CmdMiddleware)...)
+	}
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
@@ -0,0 +1,94 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestCmdMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	addr := newFakeRedis(t, func(args []string) any {
+		if calls.Add(1) == 1 {
+			return redis.Error("LOADING")
+		}
+		return args[1]
+	})
+
+	c := NewClient()
+	if err := c.ConnectByConf("main", &ConnConf{Servers: []string{addr}}); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("mw.user", "main")
+	c.RegisterKeyRoute("mw.order", "main")
+
+	var order []string
+	trace := func(name string) CmdMiddleware {
+		return func(next CmdHandler) CmdHandler {
+			return func(ctx context.Context, cmd *Cmd) (any, error) {
+				order = append(order, name)
+				return next(ctx, cmd)
+			}
+		}
+	}
+	c.Use(trace("client1"), trace("client2"))
+	c.UseRoute("mw.user", trace("route"))
+
+	// 重试一次并包装错误
+	c.Use(func(next CmdHandler) CmdHandler {
+		return func(ctx context.Context, cmd *Cmd) (any, error) {
+			res, err := next(ctx, cmd)
+			if err != nil {
+				res, err = next(ctx, cmd)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", cmd.Name, err)
+			}
+			return res, nil
+		}
+	})
+	// 短路指定key, 改写其余命令的key
+	c.UseRoute("mw.order", func(next CmdHandler) CmdHandler {
+		return func(ctx context.Context, cmd *Cmd) (any, error) {
+			if cmd.Key.Key == "cached" {
+				return "hit", nil
+			}
+			cmd.Key = cmd.Key.WithClient(c)
+			cmd.Key.Key = "rewritten"
+			return next(ctx, cmd)
+		}
+	})
+
+	get := func(key *Key) (string, error) {
+		return redis.String(DoCmdWithTTL(nil, "GET", key))
+	}
+
+	val, err := get(c.NewKey("mw.user", "1"))
+	if err != nil || val != "1" {
+		t.Fatalf("expect retried GET, got %q %v", val, err)
+	}
+	if !slices.Equal(order, []string{"client1", "client2", "route", "route"}) {
+		t.Fatalf("unexpected middleware order %v", order)
+	}
+
+	if val, err = get(c.NewKey("mw.order", "cached")); err != nil || val != "hit" {
+		t.Fatalf("expect short circuit, got %q %v", val, err)
+	}
+	if val, err = get(c.NewKey("mw.order", "1")); err != nil || val != "rewritten" {
+		t.Fatalf("expect rewritten key, got %q %v", val, err)
+	}
+
+	c.UseRoute("mw.user", func(next CmdHandler) CmdHandler {
+		return func(ctx context.Context, cmd *Cmd) (any, error) {
+			return nil, errors.New("denied")
+		}
+	})
+	if _, err = get(c.NewKey("mw.user", "1")); err == nil || err.Error() != "GET: denied" {
+		t.Fatalf("expect decorated error, got %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package routeredis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+// PipelineCmd 管道中的一条命令, Exec之后可以读取Reply和Err
+type PipelineCmd struct {
+	TTL   *TTL
+	Cmd   string
+	Key   *Key
+	Args  []any
+	Reply any
+	Err   error
+}
+
+// Pipeline 跨key的管道, Exec时按路由解析出的连接分组(集群模式下再按槽位所在节点分组),
+// 每组命令只需一次flush(带TTL的命令成功后再追加一次EXPIRE的flush), 应答按加入顺序返回
+type Pipeline struct {
+	cmds []*PipelineCmd
+}
+
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+func (p *Pipeline) Do(ttl *TTL, cmd string, key *Key, args ...any) *PipelineCmd {
+	pipelineCmd := &PipelineCmd{
+		TTL:  ttl,
+		Cmd:  cmd,
+		Key:  key,
+		Args: args,
+	}
+	p.cmds = append(p.cmds, pipelineCmd)
+	return pipelineCmd
+}
+
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+func (p *Pipeline) Cmds() []*PipelineCmd {
+	return p.cmds
+}
+
+func (p *Pipeline) Exec() ([]*PipelineCmd, error) {
+	return p.ExecContext(context.Background())
+}
+
+// ExecContext 执行管道中的全部命令, 每条命令的结果写在对应的PipelineCmd中, 返回第一个出错命令的错误
+func (p *Pipeline) ExecContext(ctx context.Context) ([]*PipelineCmd, error) {
+	var (
+		groups     []*pipelineGroup
+		groupIdxes = map[pipelineGroupKey]int{}
+	)
+	for _, cmd := range p.cmds {
+		cmd.Reply, cmd.Err = nil, nil
+
+		connName, err := KeyConnName(cmd.Key)
+		if err != nil {
+			cmd.Err = err
+			continue
+		}
+
+		pool, err := cmd.Key.Client().GetConnPool(connName)
+		if err != nil {
+			cmd.Err = err
+			continue
+		}
+
+		// 按连接池分组, 不同Client的同名连接也不会混在一起
+		groupKey := pipelineGroupKey{pool: pool}
+		cluster, isCluster := pool.(*RedisCluster)
+		if isCluster {
+			addr, err := cluster.KeyAddr(cmd.Key.RedisKey())
+			if err != nil {
+				cmd.Err = err
+				continue
+			}
+			groupKey.addr = addr
+		}
+
+		idx, ok := groupIdxes[groupKey]
+		if !ok {
+			idx = len(groups)
+			groupIdxes[groupKey] = idx
+			groups = append(groups, &pipelineGroup{connName: connName, addr: groupKey.addr, pool: pool, cluster: cluster})
+		}
+		groups[idx].cmds = append(groups[idx].cmds, cmd)
+	}
+
+	var wg sync.WaitGroup
+	for _, group := range groups {
+		wg.Add(1)
+		go func(group *pipelineGroup) {
+			defer wg.Done()
+			group.exec(ctx)
+		}(group)
+	}
+	wg.Wait()
+
+	for _, cmd := range p.cmds {
+		if cmd.Err != nil {
+			return p.cmds, cmd.Err
+		}
+	}
+
+	return p.cmds, nil
+}
+
+type pipelineGroupKey struct {
+	pool RedisPool
+	addr string // 集群模式下key所在节点
+}
+
+type pipelineGroup struct {
+	connName string
+	addr     string
+	pool     RedisPool
+	cluster  *RedisCluster
+	cmds     []*PipelineCmd
+}
+
+func (g *pipelineGroup) conn(ctx context.Context) (redis.Conn, error) {
+	if g.cluster == nil {
+		return getPoolConnContext(ctx, g.pool)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// 管道需要Send/Receive, 不能使用RetryConn, 直接绑定到槽位所在节点
+	conn := g.cluster.Cluster.Get()
+	if err := redisc.BindConn(conn, g.cmds[0].Key.RedisKey()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (g *pipelineGroup) exec(ctx context.Context) {
+	c := g.cmds[0].Key.Client()
+	if onCmdDone := c.cmdDoneHook(); onCmdDone != nil {
+		start := time.Now()
+		defer func() {
+			cost := time.Since(start)
+			for _, cmd := range g.cmds {
+				onCmdDone("Pipeline", cmd.TTL, cost, cmd.Cmd, cmd.Key, cmd.Err, cmd.Args...)
+			}
+		}()
+	}
+
+	c.pipelineHandler(g.run)(ctx, &PipelineBatch{ConnName: g.connName, Node: g.addr, Cmds: g.cmds})
+}
+
+// run 中间件链最内层的管道执行
+func (g *pipelineGroup) run(ctx context.Context, _ *PipelineBatch) {
+	g.send(ctx)
+
+	if g.cluster != nil {
+		g.retryRedirected(ctx)
+	}
+}
+
+func (g *pipelineGroup) send(ctx context.Context) {
+	conn, err := g.conn(ctx)
+	if err != nil {
+		g.fail(g.cmds, err)
+		return
+	}
+	defer conn.Close()
+
+	if err = conn.Err(); err != nil {
+		g.fail(g.cmds, err)
+		return
+	}
+
+	for _, cmd := range g.cmds {
+		if err = conn.Send(cmd.Cmd, append([]any{cmd.Key.RedisKey()}, cmd.Args...)...); err != nil {
+			g.fail(g.cmds, err)
+			return
+		}
+	}
+
+	if err = conn.Flush(); err != nil {
+		g.fail(g.cmds, err)
+		return
+	}
+
+	for i, cmd := range g.cmds {
+		cmd.Reply, cmd.Err = receiveContext(ctx, conn)
+
+		// 连接已不可用, 剩余命令的应答无法再读取
+		if connErr := conn.Err(); connErr != nil {
+			g.fail(g.cmds[i+1:], connErr)
+			return
+		}
+	}
+
+	g.expire(ctx, conn)
+}
+
+// expire 与DoCmdWithTTL一致, 只给执行成功的命令设置过期时间, 全部EXPIRE在应答读完后再作为一批发出
+func (g *pipelineGroup) expire(ctx context.Context, conn redis.Conn) {
+	var n int
+	for _, cmd := range g.cmds {
+		if cmd.Err != nil || cmd.TTL == nil || cmd.TTL.TTL <= 0 {
+			continue
+		}
+		if err := conn.Send(cmd.TTL.expireCmd(), cmd.Key.RedisKey(), cmd.TTL.TTL); err != nil {
+			return
+		}
+		n++
+	}
+	if n == 0 || conn.Flush() != nil {
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		if _, err := receiveContext(ctx, conn); err != nil && conn.Err() != nil {
+			return
+		}
+	}
+}
+
+// retryRedirected 槽位迁移导致的MOVED/ASK应答通过RetryConn逐条重试
+func (g *pipelineGroup) retryRedirected(ctx context.Context) {
+	var reset bool
+	for _, cmd := range g.cmds {
+		if redisc.ParseRedir(cmd.Err) == nil {
+			continue
+		}
+
+		if !reset {
+			g.cluster.ResetSlots()
+			reset = true
+		}
+
+		conn, err := getPoolConnContext(ctx, g.cluster)
+		if err != nil {
+			cmd.Err = err
+			continue
+		}
+		cmd.Reply, cmd.Err = doCmdWithTTL(ctx, conn, cmd.TTL, cmd.Cmd, cmd.Key.RedisKey(), cmd.Args...)
+	}
+}
+
+func (g *pipelineGroup) fail(cmds []*PipelineCmd, err error) {
+	for _, cmd := range cmds {
+		cmd.Reply, cmd.Err = nil, err
+	}
+}
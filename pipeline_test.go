@@ -0,0 +1,73 @@
+package routeredis
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestPipelineGroupsByConn(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received = map[string][]string{}
+	)
+	newServer := func(name string) string {
+		return newFakeRedis(t, func(args []string) any {
+			mu.Lock()
+			received[name] = append(received[name], args[0])
+			mu.Unlock()
+
+			switch args[0] {
+			case "GET":
+				return name + ":" + args[1]
+			case "EXPIRE":
+				return 1
+			}
+			return redis.Error("ERR unknown command")
+		})
+	}
+
+	ConnectByConf("pipeline_a", &ConnConf{Servers: []string{newServer("a")}})
+	ConnectByConf("pipeline_b", &ConnConf{Servers: []string{newServer("b")}})
+	RegisterKeyRoute("pipeline_a", "pipeline_a")
+	RegisterKeyRoute("pipeline_b", "pipeline_b")
+
+	p := NewPipeline()
+	p.Do(nil, "GET", NewKey("pipeline_a", "k1"))
+	p.Do(nil, "GET", NewKey("pipeline_b", "k2"))
+	p.Do(NewSyncSecTTL(10), "HGET", NewKey("pipeline_a", "k3"), "f")
+	p.Do(NewSyncSecTTL(10), "GET", NewKey("pipeline_a", "k4"))
+	p.Do(nil, "GET", NewKey("pipeline_unknown", "k5"))
+
+	cmds, err := p.Exec()
+	if err == nil {
+		t.Fatal("expect first command error")
+	}
+
+	expects := []string{"a:k1", "b:k2", "", "a:k4", ""}
+	for i, cmd := range cmds {
+		reply, _ := redis.String(cmd.Reply, nil)
+		if reply != expects[i] {
+			t.Errorf("cmd %d: expect %q, got %q", i, expects[i], reply)
+		}
+	}
+
+	if cmds[2].Err == nil {
+		t.Error("expect per-command error for HGET")
+	}
+	if cmds[4].Err != ErrRedisKeyRouteNotRegistered {
+		t.Errorf("expect route error, got %v", cmds[4].Err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 失败的HGET不设置过期时间, 成功的GET在应答之后补发EXPIRE
+	if got := received["a"]; !slices.Equal(got, []string{"GET", "HGET", "GET", "EXPIRE"}) {
+		t.Errorf("unexpected commands on a: %v", got)
+	}
+	if got := len(received["b"]); got != 1 {
+		t.Errorf("expect 1 command on b, got %d: %v", got, received["b"])
+	}
+}
@@ -0,0 +1,100 @@
+package routeredis
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DefaultPoolDrainTimeout = 30 * time.Second
+	poolDrainCheckInterval  = 50 * time.Millisecond
+)
+
+func SetPoolDrainTimeout(timeout time.Duration) {
+	defaultClient.SetPoolDrainTimeout(timeout)
+}
+
+// SetPoolDrainTimeout c中被替换或者移除的连接池最多等待timeout让借出的连接归还, 超时后强制关闭
+func (c *Client) SetPoolDrainTimeout(timeout time.Duration) {
+	c.poolDrainTimeout.Store(int64(timeout))
+}
+
+func PoolDrainTimeout() time.Duration {
+	return defaultClient.PoolDrainTimeout()
+}
+
+func (c *Client) PoolDrainTimeout() time.Duration {
+	return time.Duration(c.poolDrainTimeout.Load())
+}
+
+// inUseCounter 可选接口, 返回连接池当前借出未归还的连接数
+type inUseCounter interface {
+	InUseCount() int
+}
+
+func poolInUseCount(pool RedisPool) (int, bool) {
+	switch p := pool.(type) {
+	case inUseCounter:
+		return p.InUseCount(), true
+	case interface {
+		ActiveCount() int
+		IdleCount() int
+	}:
+		return p.ActiveCount() - p.IdleCount(), true
+	}
+	return 0, false
+}
+
+func (r *RedisCluster) InUseCount() int {
+	var n int
+	for _, stats := range r.Cluster.Stats() {
+		n += stats.ActiveCount - stats.IdleCount
+	}
+	return n
+}
+
+func (p *SentinelPool) InUseCount() int {
+	n := p.master.ActiveCount() - p.master.IdleCount()
+	if p.replica != nil {
+		n += p.replica.ActiveCount() - p.replica.IdleCount()
+	}
+	return n
+}
+
+type drainingPool struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// drainPool 在后台等待借出的连接归还后关闭连接池, CloseAll可以等待或者提前结束
+func (c *Client) drainPool(pool RedisPool) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.PoolDrainTimeout())
+	d := &drainingPool{cancel: cancel, done: make(chan struct{})}
+	c.drainingPools.Store(d, struct{}{})
+
+	go func() {
+		defer close(d.done)
+		defer c.drainingPools.Delete(d)
+		defer cancel()
+		drainPool(ctx, pool)
+	}()
+}
+
+// drainPool 等待借出的连接全部归还或者ctx结束后关闭连接池, 无法统计借出数的连接池等到ctx结束
+func drainPool(ctx context.Context, pool RedisPool) {
+	defer pool.Close()
+
+	ticker := time.NewTicker(poolDrainCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if n, ok := poolInUseCount(pool); ok && n <= 0 {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
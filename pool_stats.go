@@ -0,0 +1,50 @@
+package routeredis
+
+import "github.com/gomodule/redigo/redis"
+
+const (
+	SentinelMasterNode  = "master"
+	SentinelReplicaNode = "replica"
+)
+
+// PoolStats 连接池各节点的统计, 单机连接池的节点名为空, 集群为节点地址, 哨兵模式为master和replica,
+// 不支持统计的连接池返回nil
+func PoolStats(pool RedisPool) map[string]redis.PoolStats {
+	switch p := pool.(type) {
+	case *redis.Pool:
+		return map[string]redis.PoolStats{"": p.Stats()}
+	case *RedisCluster:
+		return p.Cluster.Stats()
+	case *SentinelPool:
+		return p.Stats()
+	case *DynamicConnPool:
+		if pool, err := p.client.GetConnPool(p.connName); err == nil {
+			return PoolStats(pool)
+		}
+	}
+	return nil
+}
+
+func (p *SentinelPool) Stats() map[string]redis.PoolStats {
+	stats := map[string]redis.PoolStats{SentinelMasterNode: p.master.Stats()}
+	if p.replica != nil {
+		stats[SentinelReplicaNode] = p.replica.Stats()
+	}
+	return stats
+}
+
+func ConnPoolStats() map[string]map[string]redis.PoolStats {
+	return defaultClient.ConnPoolStats()
+}
+
+// ConnPoolStats 按连接名返回c中全部连接池的PoolStats
+func (c *Client) ConnPoolStats() map[string]map[string]redis.PoolStats {
+	stats := map[string]map[string]redis.PoolStats{}
+	c.redisPools.Range(func(connName, pool any) bool {
+		if poolStats := PoolStats(pool.(RedisPool)); poolStats != nil {
+			stats[connName.(string)] = poolStats
+		}
+		return true
+	})
+	return stats
+}
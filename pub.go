@@ -1,20 +1,20 @@
 package routeredis
 
 import (
-	jsoniter "github.com/json-iterator/go"
+	"context"
 )
 
 func Publish(key *Key, data any) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return PublishCtx(context.Background(), key, data)
+}
+
+func PublishCtx(ctx context.Context, key *Key, data any) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	_, err := DoCmdWithTTL(nil, "PUBLISH", key, str)
+	_, err = DoCmdWithTTLContext(ctx, nil, "PUBLISH", key, str)
 	if err != nil {
 		return err
 	}
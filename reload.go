@@ -0,0 +1,190 @@
+package routeredis
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TopologyDiff 两次拓扑之间的差异, 名称均按字典序排列
+type TopologyDiff struct {
+	AddedConns      []string
+	ChangedConns    []string
+	RemovedConns    []string
+	AddedRoutes     []string
+	ChangedRoutes   []string
+	RemovedRoutes   []string
+	FallbackChanged bool
+}
+
+func (d *TopologyDiff) Empty() bool {
+	return len(d.AddedConns) == 0 && len(d.ChangedConns) == 0 && len(d.RemovedConns) == 0 &&
+		len(d.AddedRoutes) == 0 && len(d.ChangedRoutes) == 0 && len(d.RemovedRoutes) == 0 &&
+		!d.FallbackChanged
+}
+
+// DiffTopology prev为nil时next中的全部条目视为新增, 路由比较的是合并DefaultTTL之后的配置
+func DiffTopology(prev, next *Topology) *TopologyDiff {
+	if prev == nil {
+		prev = &Topology{}
+	}
+
+	diff := &TopologyDiff{}
+	diff.AddedConns, diff.ChangedConns, diff.RemovedConns = diffEntries(prev.Conns, next.Conns, func(a, b *ConnConf) bool {
+		return reflect.DeepEqual(a, b)
+	})
+	diff.AddedRoutes, diff.ChangedRoutes, diff.RemovedRoutes = diffEntries(prev.Routes, next.Routes, func(a, b *RouteConf) bool {
+		return reflect.DeepEqual(prev.effectiveRoute(a), next.effectiveRoute(b))
+	})
+	diff.FallbackChanged = !reflect.DeepEqual(prev.effectiveRoute(prev.Fallback), next.effectiveRoute(next.Fallback))
+
+	return diff
+}
+
+func diffEntries[V any](prev, next map[string]V, equal func(a, b V) bool) (added, changed, removed []string) {
+	for _, name := range sortedKeys(next) {
+		old, ok := prev[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case !equal(old, next[name]):
+			changed = append(changed, name)
+		}
+	}
+	for _, name := range sortedKeys(prev) {
+		if _, ok := next[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	return added, changed, removed
+}
+
+func (t *Topology) effectiveRoute(conf *RouteConf) *RouteConf {
+	if conf == nil {
+		return nil
+	}
+	effective := *conf
+	effective.TTL = t.routeTTL(conf)
+	return &effective
+}
+
+type topologyListener struct {
+	fn func(diff *TopologyDiff)
+}
+
+func OnTopologyChange(fn func(diff *TopologyDiff)) func() {
+	return defaultClient.OnTopologyChange(fn)
+}
+
+func CurrentTopology() *Topology {
+	return defaultClient.CurrentTopology()
+}
+
+// Apply 将拓扑应用到DefaultClient
+func (t *Topology) Apply() error {
+	return defaultClient.ApplyTopology(t)
+}
+
+func ReloadTopology(next *Topology) (*TopologyDiff, error) {
+	return defaultClient.ReloadTopology(next)
+}
+
+// OnTopologyChange 每次ApplyTopology或者ReloadTopology产生变化后回调fn, 返回的函数用于移除监听
+func (c *Client) OnTopologyChange(fn func(diff *TopologyDiff)) func() {
+	listener := &topologyListener{fn: fn}
+	c.topologyListeners.Store(listener, struct{}{})
+	return func() {
+		c.topologyListeners.Delete(listener)
+	}
+}
+
+// CurrentTopology 最近一次ApplyTopology或者ReloadTopology成功应用的拓扑, 不要修改返回值
+func (c *Client) CurrentTopology() *Topology {
+	c.topologyMu.Lock()
+	defer c.topologyMu.Unlock()
+	return c.currentTopology
+}
+
+// ApplyTopology 校验后按名称顺序建立全部连接并注册全部路由, 之前通过ApplyTopology或者ReloadTopology注册而本次不存在的路由和连接会被移除,
+// 出错时返回带条目路径的错误
+func (c *Client) ApplyTopology(t *Topology) error {
+	_, err := c.reloadTopology(t, true)
+	return err
+}
+
+// ReloadTopology 与当前拓扑比较, 只重建新增和变化的连接池, 注册变化的路由, 移除删除的路由和连接,
+// 被替换和移除的连接池按PoolDrainTimeout等待借出的连接归还后关闭
+func (c *Client) ReloadTopology(next *Topology) (*TopologyDiff, error) {
+	return c.reloadTopology(next, false)
+}
+
+func (c *Client) reloadTopology(next *Topology, full bool) (*TopologyDiff, error) {
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+
+	c.topologyMu.Lock()
+	defer c.topologyMu.Unlock()
+
+	diff := DiffTopology(c.currentTopology, next)
+	toApply := diff
+	if full {
+		toApply = DiffTopology(nil, next)
+		toApply.FallbackChanged = true
+		toApply.RemovedConns, toApply.RemovedRoutes = diff.RemovedConns, diff.RemovedRoutes
+	}
+	if err := c.applyTopologyDiff(next, toApply); err != nil {
+		return nil, err
+	}
+
+	c.currentTopology = next
+
+	if !diff.Empty() {
+		c.topologyListeners.Range(func(key, _ any) bool {
+			key.(*topologyListener).fn(diff)
+			return true
+		})
+	}
+
+	return diff, nil
+}
+
+func (c *Client) applyTopologyDiff(t *Topology, diff *TopologyDiff) error {
+	for _, names := range [][]string{diff.AddedConns, diff.ChangedConns} {
+		for _, connName := range names {
+			if err := c.ConnectByConf(connName, t.Conns[connName]); err != nil {
+				return wrapEntryErr("conns."+connName, err)
+			}
+		}
+	}
+
+	for _, names := range [][]string{diff.AddedRoutes, diff.ChangedRoutes} {
+		for _, route := range names {
+			if err := t.applyRoute(c, route, t.Routes[route]); err != nil {
+				return wrapEntryErr("routes."+route, err)
+			}
+		}
+	}
+
+	if diff.FallbackChanged {
+		if t.Fallback == nil {
+			c.SetFallbackKeyRoute("", nil)
+		} else {
+			c.SetFallbackKeyRoute(t.Fallback.Conn, t.routeOpts(t.Fallback))
+		}
+	}
+
+	// 先摘除路由再移除连接, 避免路由解析到已移除的连接
+	for _, route := range diff.RemovedRoutes {
+		_ = c.UnregisterKeyRoute(route)
+	}
+
+	for _, connName := range diff.RemovedConns {
+		_ = c.Disconnect(connName)
+	}
+
+	return nil
+}
+
+func wrapEntryErr(entry string, err error) error {
+	return fmt.Errorf("%s: %w", entry, err)
+}
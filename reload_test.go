@@ -0,0 +1,104 @@
+package routeredis
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReloadTopology(t *testing.T) {
+	addr := newFakeRedis(t, func(args []string) any {
+		return "OK"
+	})
+
+	topo := &Topology{
+		Conns: map[string]*ConnConf{
+			"reload.a": {Servers: []string{addr}},
+			"reload.b": {Servers: []string{addr}},
+		},
+		Routes: map[string]*RouteConf{
+			"reload.user":  {Conn: "reload.a"},
+			"reload.order": {Conn: "reload.b"},
+		},
+	}
+	if err := topo.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	var diffs []*TopologyDiff
+	remove := OnTopologyChange(func(diff *TopologyDiff) {
+		diffs = append(diffs, diff)
+	})
+	defer remove()
+
+	oldPool, _ := defaultClient.redisPools.Load("reload.a")
+
+	next := &Topology{
+		Conns: map[string]*ConnConf{
+			"reload.a": {Servers: []string{addr}, KeyPrefix: "v2:"},
+		},
+		Routes: map[string]*RouteConf{
+			"reload.user": {Conn: "reload.a"},
+		},
+	}
+	diff, err := ReloadTopology(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(diff.ChangedConns, []string{"reload.a"}) || !slices.Equal(diff.RemovedConns, []string{"reload.b"}) ||
+		!slices.Equal(diff.RemovedRoutes, []string{"reload.order"}) || len(diff.ChangedRoutes) != 0 {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	if len(diffs) != 1 || CurrentTopology() != next {
+		t.Fatalf("expect one notification and current topology updated")
+	}
+
+	if _, err = RouteConnName("reload.order"); !errors.Is(err, ErrRedisKeyRouteNotRegistered) {
+		t.Fatalf("expect removed route, got %v", err)
+	}
+	if got := NewKey("reload.user", "1").RedisKey(); got != "v2:1" {
+		t.Fatalf("unexpected redis key %s", got)
+	}
+	if pool, _ := defaultClient.redisPools.Load("reload.a"); pool == oldPool {
+		t.Fatal("expect changed conn to be rebuilt")
+	}
+
+	if diff, err = ReloadTopology(next); err != nil || !diff.Empty() || len(diffs) != 1 {
+		t.Fatalf("expect empty diff without notification, got %+v %v", diff, err)
+	}
+}
+
+func TestDrainPool(t *testing.T) {
+	addr := newFakeRedis(t, func(args []string) any {
+		return "OK"
+	})
+
+	SetPoolDrainTimeout(time.Second)
+	defer SetPoolDrainTimeout(DefaultPoolDrainTimeout)
+
+	const connName = "drain"
+	ConnectByConf(connName, &ConnConf{Servers: []string{addr}})
+	v, _ := defaultClient.redisPools.Load(connName)
+	pool := v.(RedisPool)
+
+	conn := pool.Get()
+	if _, err := conn.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+
+	ConnectByConf(connName, &ConnConf{Servers: []string{addr}})
+
+	// 借出的连接归还前旧连接池不会关闭
+	time.Sleep(3 * poolDrainCheckInterval)
+	if _, err := conn.Do("PING"); err != nil {
+		t.Fatalf("expect in-flight conn usable during drain, got %v", err)
+	}
+	_ = conn.Close()
+
+	time.Sleep(3 * poolDrainCheckInterval)
+	if _, err := pool.Get().Do("PING"); err == nil {
+		t.Fatal("expect old pool closed after drain")
+	}
+}
@@ -0,0 +1,65 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+type readFromPrimaryCtxKey struct{}
+
+// WithReadFromPrimary 返回的ctx下只读命令也发往主库, 用于写后立即读取等需要读己之写的场景,
+// 对路由的Replicas以及哨兵模式的ReadFromReplicas同样生效
+func WithReadFromPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readFromPrimaryCtxKey{}, true)
+}
+
+func isReadFromPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(readFromPrimaryCtxKey{}).(bool)
+	return v
+}
+
+// doReplicaCmd 路由配置了从库时, 从轮询到的从库开始依次尝试, 从库取连接失败或者网络出错时换下一个,
+// 全部不可用时返回false由调用方退回主库, redis返回的错误不重试
+func doReplicaCmd(ctx context.Context, key *Key, cmd string, args ...any) (any, bool, error) {
+	if isReadFromPrimary(ctx) {
+		return nil, false, nil
+	}
+
+	c := key.Client()
+	r, ok := c.lookupKeyRoute(key.Route)
+	if !ok || len(r.replicas) == 0 {
+		return nil, false, nil
+	}
+
+	redisKey := key.RedisKey()
+	start := r.replicaIdx.Add(1)
+	for i := range r.replicas {
+		pool, err := c.GetConnPool(r.replicas[(start+uint64(i))%uint64(len(r.replicas))])
+		if err != nil {
+			continue
+		}
+
+		conn, err := getPoolConnContext(ctx, pool)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, true, err
+			}
+			continue
+		}
+
+		if conn.Err() != nil {
+			conn.Close()
+			continue
+		}
+
+		res, err := doCmdWithTTL(ctx, conn, nil, cmd, redisKey, args...)
+		var redisErr redis.Error
+		if err == nil || errors.As(err, &redisErr) || ctx.Err() != nil {
+			return res, true, err
+		}
+	}
+
+	return nil, false, nil
+}
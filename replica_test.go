@@ -0,0 +1,43 @@
+package routeredis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestReplicaReads(t *testing.T) {
+	primary := newFakeRedis(t, func(args []string) any {
+		return "primary"
+	})
+	replica := newFakeRedis(t, func(args []string) any {
+		return "replica"
+	})
+	ConnectByConf("rw.primary", &ConnConf{Servers: []string{primary}})
+	ConnectByConf("rw.replica", &ConnConf{Servers: []string{replica}})
+	ConnectByConf("rw.down", &ConnConf{Servers: []string{"127.0.0.1:1"}})
+
+	RegisterKeyRouteWithOpts("rw", "rw.primary", &KeyRouteOpts{Replicas: []string{"rw.replica", "rw.down", "rw.missing"}})
+	key := NewKey("rw", "foo")
+
+	for i := 0; i < 3; i++ {
+		if res, err := redis.String(DoCmdWithTTL(nil, "GET", key)); err != nil || res != "replica" {
+			t.Fatalf("expect read from replica, got %q %v", res, err)
+		}
+	}
+
+	if res, err := redis.String(DoCmdWithTTL(nil, "SET", key, "bar")); err != nil || res != "primary" {
+		t.Fatalf("expect write to primary, got %q %v", res, err)
+	}
+
+	ctx := WithReadFromPrimary(context.Background())
+	if res, err := redis.String(DoCmdWithTTLContext(ctx, nil, "GET", key)); err != nil || res != "primary" {
+		t.Fatalf("expect read from primary, got %q %v", res, err)
+	}
+
+	RegisterKeyRouteWithOpts("rw.fallback", "rw.primary", &KeyRouteOpts{Replicas: []string{"rw.down"}})
+	if res, err := redis.String(DoCmdWithTTL(nil, "GET", NewKey("rw.fallback", "foo"))); err != nil || res != "primary" {
+		t.Fatalf("expect fallback to primary, got %q %v", res, err)
+	}
+}
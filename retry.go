@@ -0,0 +1,168 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	DefaultRetryMaxAttempts       = 3
+	DefaultRetryMinBackoffMillSec = 10
+	DefaultRetryMaxBackoffMillSec = 500
+	DefaultRetryJitter            = 0.2
+	DefaultClusterMaxRedirects    = 10
+	defaultClusterTryAgainDelay   = 10 * time.Millisecond
+)
+
+// DefaultRetryableErrors RetryPolicy.RetryableErrors为空时可重试的服务端错误前缀
+var DefaultRetryableErrors = []string{"LOADING", "TRYAGAIN", "CLUSTERDOWN"}
+
+// RetryPolicy DoCmdWithTTL遇到临时错误时的重试策略, 单机, 哨兵与集群模式一致生效.
+// 服务端以RetryableErrors拒绝的命令没有执行, 总是可以重试; 连接重置等网络错误发生时命令可能已经执行,
+// 只重试IsIdempotentCmd的命令, 避免INCRBY, LPUSH, SET NX等被重复执行
+type RetryPolicy struct {
+	MaxAttempts         int      // 包含首次执行的总次数, <=0时取DefaultRetryMaxAttempts, 1为不重试
+	MinBackoffMillSec   int      // 首次重试前的等待, 之后每次翻倍, <=0时取DefaultRetryMinBackoffMillSec
+	MaxBackoffMillSec   int      // <=0时取DefaultRetryMaxBackoffMillSec
+	Jitter              float64  // 等待时间随机减少的最大比例(0~1), 0为不随机, <0时取DefaultRetryJitter
+	RetryableErrors     []string // 可重试的服务端错误前缀, 为空时取DefaultRetryableErrors
+	RetryNonIdempotent  bool     // 网络错误时也重试非幂等命令, 可能导致命令重复执行
+	ClusterMaxRedirects int      // 集群模式跟随MOVED/ASK的最大次数, <=0时取DefaultClusterMaxRedirects
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultRetryMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// backoff 第attempt次执行失败后的等待时间
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	minBackoff, maxBackoff := p.MinBackoffMillSec, p.MaxBackoffMillSec
+	if minBackoff <= 0 {
+		minBackoff = DefaultRetryMinBackoffMillSec
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoffMillSec
+	}
+	jitter := p.Jitter
+	if jitter < 0 {
+		jitter = DefaultRetryJitter
+	}
+
+	backoff := time.Duration(minBackoff) * time.Millisecond
+	for i := 1; i < attempt && backoff < time.Duration(maxBackoff)*time.Millisecond; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, time.Duration(maxBackoff)*time.Millisecond)
+
+	return backoff - time.Duration(rand.Float64()*min(jitter, 1)*float64(backoff))
+}
+
+func (p *RetryPolicy) clusterMaxRedirects() int {
+	if p == nil || p.ClusterMaxRedirects <= 0 {
+		return DefaultClusterMaxRedirects
+	}
+	return p.ClusterMaxRedirects
+}
+
+// clusterTryAgainDelay 集群模式下redisc遇到TRYAGAIN时的等待
+func (p *RetryPolicy) clusterTryAgainDelay() time.Duration {
+	if p == nil || p.MinBackoffMillSec <= 0 {
+		return defaultClusterTryAgainDelay
+	}
+	return time.Duration(p.MinBackoffMillSec) * time.Millisecond
+}
+
+// shouldRetry sent为false表示命令还没有发出(取连接失败)
+func (p *RetryPolicy) shouldRetry(cmd string, args []any, err error, sent bool) bool {
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		retryableErrors := p.RetryableErrors
+		if len(retryableErrors) == 0 {
+			retryableErrors = DefaultRetryableErrors
+		}
+		for _, prefix := range retryableErrors {
+			if strings.HasPrefix(string(redisErr), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !isNetworkErr(err) {
+		return false
+	}
+
+	return !sent || p.RetryNonIdempotent || IsIdempotentCmd(cmd, args...)
+}
+
+func isNetworkErr(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+func SetConnRetryPolicy(connName string, policy *RetryPolicy) {
+	defaultClient.SetConnRetryPolicy(connName, policy)
+}
+
+// SetConnRetryPolicy 设置连接的重试策略, policy为空时不重试, ConnectByConf时取ConnConf.Retry
+func (c *Client) SetConnRetryPolicy(connName string, policy *RetryPolicy) {
+	if policy == nil {
+		c.connRetryPolicies.Delete(connName)
+		return
+	}
+	c.connRetryPolicies.Store(connName, policy)
+}
+
+func (c *Client) connRetryPolicy(connName string) (*RetryPolicy, bool) {
+	policy, ok := c.connRetryPolicies.Load(connName)
+	if !ok {
+		return nil, false
+	}
+	return policy.(*RetryPolicy), true
+}
+
+// doCmdWithRetry 按key所在连接的重试策略执行do, 每次执行都重新取连接
+func doCmdWithRetry(ctx context.Context, key *Key, cmd string, args []any, do func() (any, bool, error)) (any, error) {
+	connName, err := KeyConnName(key)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, ok := key.Client().connRetryPolicy(connName)
+	if !ok {
+		res, _, err := do()
+		return res, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, sent, err := do()
+		if err == nil || attempt >= policy.maxAttempts() || !policy.shouldRetry(cmd, args, err, sent) {
+			return res, err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
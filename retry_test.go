@@ -0,0 +1,117 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		fails = map[string]any{}
+		calls = map[string]int{}
+	)
+	addr := newFakeRedis(t, func(args []string) any {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls[args[0]]++
+		if reply, ok := fails[args[0]]; ok {
+			delete(fails, args[0])
+			return reply
+		}
+		if args[0] == "INCRBY" {
+			return 1
+		}
+		return "v"
+	})
+	failOnce := func(cmd string, reply any) {
+		mu.Lock()
+		defer mu.Unlock()
+		fails[cmd], calls[cmd] = reply, 0
+	}
+	callCount := func(cmd string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[cmd]
+	}
+
+	c := NewClient()
+	err := c.ConnectByConf("main", &ConnConf{
+		Servers: []string{addr},
+		Retry:   &RetryPolicy{MaxAttempts: 2, MinBackoffMillSec: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("retry", "main")
+	key := c.NewKey("retry", "k")
+
+	// 服务端拒绝的命令没有执行, 幂等与否都重试
+	failOnce("GET", redis.Error("LOADING Redis is loading the dataset in memory"))
+	if val, err := redis.String(DoCmdWithTTL(nil, "GET", key)); err != nil || val != "v" || callCount("GET") != 2 {
+		t.Fatalf("expect GET retried after LOADING, got %q %v calls %d", val, err, callCount("GET"))
+	}
+	failOnce("INCRBY", redis.Error("TRYAGAIN Multiple keys request during rehashing of slot"))
+	if _, err = DoCmdWithTTL(nil, "INCRBY", key, 1); err != nil || callCount("INCRBY") != 2 {
+		t.Fatalf("expect INCRBY retried after TRYAGAIN, got %v calls %d", err, callCount("INCRBY"))
+	}
+
+	// 连接重置时命令可能已经执行, 只重试幂等命令
+	failOnce("GET", fakeRedisCloseConn)
+	if val, err := redis.String(DoCmdWithTTL(nil, "GET", key)); err != nil || val != "v" || callCount("GET") != 2 {
+		t.Fatalf("expect GET retried after conn reset, got %q %v calls %d", val, err, callCount("GET"))
+	}
+	failOnce("INCRBY", fakeRedisCloseConn)
+	if _, err = DoCmdWithTTL(nil, "INCRBY", key, 1); err == nil || callCount("INCRBY") != 1 {
+		t.Fatalf("expect INCRBY not retried after conn reset, got %v calls %d", err, callCount("INCRBY"))
+	}
+
+	// SET NX重放时key已被首次执行写入, 会得到nil而误判为加锁失败, 应返回错误由调用方处理
+	failOnce("SET", fakeRedisCloseConn)
+	if ok, err := Setnx(key, "v", 10); err == nil || ok || callCount("SET") != 1 {
+		t.Fatalf("expect SET NX not retried after conn reset, got %v %v calls %d", ok, err, callCount("SET"))
+	}
+	failOnce("SET", fakeRedisCloseConn)
+	if ok, err := NewLock(key, time.Second).TryAcquire(context.Background()); err == nil || ok || callCount("SET") != 1 {
+		t.Fatalf("expect lock SET NX not retried after conn reset, got %v %v calls %d", ok, err, callCount("SET"))
+	}
+	failOnce("SET", fakeRedisCloseConn)
+	if err = Set(key, "v", 0); err != nil || callCount("SET") != 2 {
+		t.Fatalf("expect plain SET retried after conn reset, got %v calls %d", err, callCount("SET"))
+	}
+
+	// 不可重试的错误以及超过MaxAttempts直接返回
+	failOnce("GET", redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value"))
+	if _, err = DoCmdWithTTL(nil, "GET", key); err == nil || callCount("GET") != 1 {
+		t.Fatalf("expect WRONGTYPE not retried, got %v calls %d", err, callCount("GET"))
+	}
+
+	c.SetConnRetryPolicy("main", nil)
+	failOnce("GET", redis.Error("LOADING"))
+	var redisErr redis.Error
+	if _, err = DoCmdWithTTL(nil, "GET", key); !errors.As(err, &redisErr) || callCount("GET") != 1 {
+		t.Fatalf("expect no retry without policy, got %v calls %d", err, callCount("GET"))
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{MinBackoffMillSec: 10, MaxBackoffMillSec: 40, Jitter: 0.5}
+	for attempt, want := range map[int]int{1: 10, 2: 20, 3: 40, 10: 40} {
+		backoff := p.backoff(attempt).Milliseconds()
+		if backoff > int64(want) || backoff < int64(want/2) {
+			t.Fatalf("attempt %d: expect backoff in [%d, %d]ms, got %dms", attempt, want/2, want, backoff)
+		}
+	}
+
+	// Jitter为0时不随机
+	p.Jitter = 0
+	if backoff := p.backoff(2); backoff != 20*time.Millisecond {
+		t.Fatalf("expect exact backoff without jitter, got %v", backoff)
+	}
+}
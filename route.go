@@ -1,8 +1,10 @@
 package routeredis
 
 import (
+	"context"
 	"fmt"
-	"sync"
+	"slices"
+	"sync/atomic"
 
 	"github.com/gomodule/redigo/redis"
 )
@@ -17,24 +19,180 @@ func NewKey(route string, tmpl string, args ...any) *Key {
 type Key struct {
 	Route string
 	Key   string
+	c     *Client
 }
 
-var keyRoutes sync.Map
+// Client key所属的Client, 通过NewKey创建或者直接构造的key属于DefaultClient
+func (k *Key) Client() *Client {
+	if k.c == nil {
+		return defaultClient
+	}
+	return k.c
+}
+
+// WithClient 返回属于c的key副本, 用于把KeyTemplate等生成的key交给其他Client执行
+func (k *Key) WithClient(c *Client) *Key {
+	key := *k
+	key.c = c
+	return &key
+}
+
+// RedisKey 实际发往redis的key, 依次拼接连接的KeyPrefix, 路由的Prefix和Key
+func (k *Key) RedisKey() string {
+	c := k.Client()
+	r, ok := c.lookupKeyRoute(k.Route)
+	if !ok {
+		return k.Key
+	}
+	return c.keyPrefix(r, r.keyConnName(k.Key)) + k.Key
+}
+
+type KeyRouteOpts struct {
+	Prefix   string   // 路由的key前缀, 拼接在连接的KeyPrefix之后
+	Replicas []string // 从库连接名, 只读命令轮询发往从库, 从库不可用时退回主库
+	TTL      int64    // 路由下key的默认过期秒数, 通过RouteTTL读取
+}
+
+type keyRoute struct {
+	connName   string
+	prefix     string
+	pattern    string    // 通配规则的格式, 精确路由为空
+	seq        int64     // 通配规则的注册顺序
+	ring       *hashRing // 分片路由按key选择连接, connName为空
+	replicas   []string
+	ttl        int64
+	replicaIdx *atomic.Uint64 // 从库轮询计数
+}
+
+func newKeyRoute(connName string, opts *KeyRouteOpts) *keyRoute {
+	r := &keyRoute{connName: connName}
+	if opts != nil {
+		r.prefix, r.ttl = opts.Prefix, opts.TTL
+		if len(opts.Replicas) > 0 {
+			r.replicas = slices.Clone(opts.Replicas)
+			r.replicaIdx = &atomic.Uint64{}
+		}
+	}
+	return r
+}
+
+func (r *keyRoute) keyConnName(key string) string {
+	if r.ring != nil {
+		return r.ring.get(key)
+	}
+	return r.connName
+}
+
+// keyPrefix 路由在指定连接上的key前缀
+func (c *Client) keyPrefix(r *keyRoute, connName string) string {
+	if connPrefix, ok := c.connKeyPrefixes.Load(connName); ok {
+		return connPrefix.(string) + r.prefix
+	}
+	return r.prefix
+}
 
 func RegisterKeyRoute(route string, connName string) {
-	keyRoutes.Store(route, connName)
+	defaultClient.RegisterKeyRoute(route, connName)
+}
+
+func RegisterKeyRouteWithOpts(route string, connName string, opts *KeyRouteOpts) {
+	defaultClient.RegisterKeyRouteWithOpts(route, connName, opts)
 }
 
 func RegisterDefaultConnKeyRoute(route string) {
-	RegisterKeyRoute(route, DefaultConnName)
+	defaultClient.RegisterKeyRoute(route, DefaultConnName)
 }
 
-func RouteConnPool(route string) (RedisPool, error) {
-	connName, ok := keyRoutes.Load(route)
+func (c *Client) RegisterKeyRoute(route string, connName string) {
+	c.RegisterKeyRouteWithOpts(route, connName, nil)
+}
+
+func (c *Client) RegisterKeyRouteWithOpts(route string, connName string, opts *KeyRouteOpts) {
+	c.keyRoutes.Store(route, newKeyRoute(connName, opts))
+}
+
+func RouteConnName(route string) (string, error) {
+	return defaultClient.RouteConnName(route)
+}
+
+func (c *Client) RouteConnName(route string) (string, error) {
+	r, ok := c.lookupKeyRoute(route)
+	if !ok {
+		return "", ErrRedisKeyRouteNotRegistered
+	}
+	if r.ring != nil {
+		return "", ErrKeyRouteSharded
+	}
+	return r.connName, nil
+}
+
+func RouteTTL(route string) int64 {
+	return defaultClient.RouteTTL(route)
+}
+
+// RouteTTL 注册路由时配置的默认过期秒数, 未配置时为0
+func (c *Client) RouteTTL(route string) int64 {
+	r, ok := c.lookupKeyRoute(route)
+	if !ok {
+		return 0
+	}
+	return r.ttl
+}
+
+// KeyConnName 按key解析连接名, 分片路由按Key.Key的一致性哈希选择连接
+func KeyConnName(key *Key) (string, error) {
+	r, ok := key.Client().lookupKeyRoute(key.Route)
+	if !ok {
+		return "", ErrRedisKeyRouteNotRegistered
+	}
+	return r.keyConnName(key.Key), nil
+}
+
+func SetConnKeyPrefix(connName string, prefix string) {
+	defaultClient.SetConnKeyPrefix(connName, prefix)
+}
+
+// SetConnKeyPrefix 设置连接下所有key的前缀, 通常用于区分共用redis的不同环境, ConnectByConf时取ConnConf.KeyPrefix
+func (c *Client) SetConnKeyPrefix(connName string, prefix string) {
+	if prefix == "" {
+		c.connKeyPrefixes.Delete(connName)
+		return
+	}
+	c.connKeyPrefixes.Store(connName, prefix)
+}
+
+func KeyPrefix(route string) string {
+	return defaultClient.KeyPrefix(route)
+}
+
+// KeyPrefix 路由下key的完整前缀, 路由未注册时为空, 分片路由的各分片连接前缀可能不同, 这里只返回路由的Prefix
+func (c *Client) KeyPrefix(route string) string {
+	r, ok := c.lookupKeyRoute(route)
+	if !ok {
+		return ""
+	}
+	return c.keyPrefix(r, r.connName)
+}
+
+// connKeyPrefix 路由在指定连接上的key前缀
+func (c *Client) connKeyPrefix(route string, connName string) string {
+	r, ok := c.lookupKeyRoute(route)
 	if !ok {
-		return nil, ErrRedisKeyRouteNotRegistered
+		return ""
+	}
+	return c.keyPrefix(r, connName)
+}
+
+func RouteConnPool(route string) (RedisPool, error) {
+	return defaultClient.RouteConnPool(route)
+}
+
+func (c *Client) RouteConnPool(route string) (RedisPool, error) {
+	connName, err := c.RouteConnName(route)
+	if err != nil {
+		return nil, err
 	}
-	return GetConnPool(connName.(string))
+	return c.GetConnPool(connName)
 }
 
 func RouteConn(route string) (redis.Conn, error) {
@@ -44,3 +202,27 @@ func RouteConn(route string) (redis.Conn, error) {
 	}
 	return pool.Get(), nil
 }
+
+func RouteConnContext(ctx context.Context, route string) (redis.Conn, error) {
+	pool, err := RouteConnPool(route)
+	if err != nil {
+		return nil, err
+	}
+	return getPoolConnContext(ctx, pool)
+}
+
+func KeyConnPool(key *Key) (RedisPool, error) {
+	connName, err := KeyConnName(key)
+	if err != nil {
+		return nil, err
+	}
+	return key.Client().GetConnPool(connName)
+}
+
+func KeyConnContext(ctx context.Context, key *Key) (redis.Conn, error) {
+	pool, err := KeyConnPool(key)
+	if err != nil {
+		return nil, err
+	}
+	return getPoolConnContext(ctx, pool)
+}
@@ -0,0 +1,195 @@
+package routeredis
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type KeyRouteMatchKind string
+
+const (
+	KeyRouteMatchExact    KeyRouteMatchKind = "exact"
+	KeyRouteMatchPattern  KeyRouteMatchKind = "pattern"
+	KeyRouteMatchFallback KeyRouteMatchKind = "fallback"
+)
+
+// KeyRouteMatch 路由的解析结果, 用于排查路由命中了哪条规则
+type KeyRouteMatch struct {
+	Route    string
+	ConnName string
+	Prefix   string
+	Kind     KeyRouteMatchKind
+	Pattern  string   // Kind为pattern时命中的规则
+	Shards   []string // 分片路由的全部分片连接, 此时ConnName为空
+	Replicas []string
+}
+
+// RegisterKeyRoutePattern 注册通配路由规则, 语法同path.Match, 例如"user.*"匹配所有"user."开头的路由.
+// 路由解析顺序: 精确路由 > 通配规则 > 兜底连接, 多条通配规则同时命中时, 第一个通配符之前的字面量越长越优先,
+// 相同时先注册的优先, 重复注册同一格式会覆盖之前的规则
+func RegisterKeyRoutePattern(pattern string, connName string) error {
+	return defaultClient.RegisterKeyRoutePatternWithOpts(pattern, connName, nil)
+}
+
+func RegisterKeyRoutePatternWithOpts(pattern string, connName string, opts *KeyRouteOpts) error {
+	return defaultClient.RegisterKeyRoutePatternWithOpts(pattern, connName, opts)
+}
+
+func (c *Client) RegisterKeyRoutePattern(pattern string, connName string) error {
+	return c.RegisterKeyRoutePatternWithOpts(pattern, connName, nil)
+}
+
+func (c *Client) RegisterKeyRoutePatternWithOpts(pattern string, connName string, opts *KeyRouteOpts) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	c.keyRouteRuleMu.Lock()
+	defer c.keyRouteRuleMu.Unlock()
+
+	c.keyRouteSeq++
+	r := newKeyRoute(connName, opts)
+	r.pattern, r.seq = pattern, c.keyRouteSeq
+
+	patterns := make([]*keyRoute, 0, len(c.keyRoutePatterns)+1)
+	for _, item := range c.keyRoutePatterns {
+		if item.pattern == pattern {
+			r.seq = item.seq
+			continue
+		}
+		patterns = append(patterns, item)
+	}
+	patterns = append(patterns, r)
+	sort.SliceStable(patterns, func(i, j int) bool {
+		li, lj := patternLiteralLen(patterns[i].pattern), patternLiteralLen(patterns[j].pattern)
+		if li != lj {
+			return li > lj
+		}
+		return patterns[i].seq < patterns[j].seq
+	})
+	c.keyRoutePatterns = patterns
+
+	c.resetResolvedKeyRoutes()
+
+	return nil
+}
+
+func (c *Client) removeKeyRoutePattern(pattern string) bool {
+	c.keyRouteRuleMu.Lock()
+	defer c.keyRouteRuleMu.Unlock()
+
+	n := len(c.keyRoutePatterns)
+	c.keyRoutePatterns = slices.DeleteFunc(slices.Clone(c.keyRoutePatterns), func(r *keyRoute) bool {
+		return r.pattern == pattern
+	})
+
+	c.resetResolvedKeyRoutes()
+
+	return len(c.keyRoutePatterns) != n
+}
+
+func SetFallbackKeyRoute(connName string, opts *KeyRouteOpts) {
+	defaultClient.SetFallbackKeyRoute(connName, opts)
+}
+
+// SetFallbackKeyRoute 未注册的路由使用connName连接, connName为空时取消兜底, 未命中时返回ErrRedisKeyRouteNotRegistered
+func (c *Client) SetFallbackKeyRoute(connName string, opts *KeyRouteOpts) {
+	c.keyRouteRuleMu.Lock()
+	defer c.keyRouteRuleMu.Unlock()
+
+	c.fallbackKeyRoute = nil
+	if connName != "" {
+		c.fallbackKeyRoute = newKeyRoute(connName, opts)
+	}
+
+	c.resetResolvedKeyRoutes()
+}
+
+func KeyRoutePatterns() []string {
+	return defaultClient.KeyRoutePatterns()
+}
+
+// KeyRoutePatterns 按匹配顺序返回已注册的通配规则
+func (c *Client) KeyRoutePatterns() []string {
+	c.keyRouteRuleMu.RLock()
+	defer c.keyRouteRuleMu.RUnlock()
+
+	patterns := make([]string, 0, len(c.keyRoutePatterns))
+	for _, r := range c.keyRoutePatterns {
+		patterns = append(patterns, r.pattern)
+	}
+	return patterns
+}
+
+func ResolveKeyRoute(route string) (*KeyRouteMatch, error) {
+	return defaultClient.ResolveKeyRoute(route)
+}
+
+func (c *Client) ResolveKeyRoute(route string) (*KeyRouteMatch, error) {
+	r, ok := c.lookupKeyRoute(route)
+	if !ok {
+		return nil, ErrRedisKeyRouteNotRegistered
+	}
+
+	match := &KeyRouteMatch{
+		Route:    route,
+		ConnName: r.connName,
+		Prefix:   c.KeyPrefix(route),
+		Kind:     KeyRouteMatchExact,
+		Replicas: slices.Clone(r.replicas),
+	}
+	if r.ring != nil {
+		match.Shards = slices.Clone(r.ring.connNames)
+	}
+	if _, ok = c.keyRoutes.Load(route); !ok {
+		match.Kind, match.Pattern = KeyRouteMatchFallback, r.pattern
+		if r.pattern != "" {
+			match.Kind = KeyRouteMatchPattern
+		}
+	}
+
+	return match, nil
+}
+
+func (c *Client) lookupKeyRoute(route string) (*keyRoute, bool) {
+	if r, ok := c.keyRoutes.Load(route); ok {
+		return r.(*keyRoute), true
+	}
+
+	if r, ok := c.resolvedKeyRoutes.Load(route); ok {
+		return r.(*keyRoute), true
+	}
+
+	c.keyRouteRuleMu.RLock()
+	defer c.keyRouteRuleMu.RUnlock()
+
+	for _, r := range c.keyRoutePatterns {
+		if ok, _ := path.Match(r.pattern, route); ok {
+			c.resolvedKeyRoutes.Store(route, r)
+			return r, true
+		}
+	}
+
+	if c.fallbackKeyRoute == nil {
+		return nil, false
+	}
+
+	// 兜底结果不缓存, 避免任意路由名撑大缓存
+	return c.fallbackKeyRoute, true
+}
+
+func (c *Client) resetResolvedKeyRoutes() {
+	c.resolvedKeyRoutes.Range(func(k, _ any) bool {
+		c.resolvedKeyRoutes.Delete(k)
+		return true
+	})
+}
+
+func patternLiteralLen(pattern string) int {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return i
+	}
+	return len(pattern)
+}
@@ -0,0 +1,104 @@
+package routeredis
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+	addr := newFakeRedis(t, func(args []string) any {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch args[0] {
+		case "SET":
+			seen = append(seen, args[1])
+			return "OK"
+		case "SCAN":
+			seen = append(seen, args[3])
+			if args[1] == "0" {
+				return []any{"7", []any{"test:order:1"}}
+			}
+			return []any{"0", []any{"test:order:2"}}
+		}
+		return nil
+	})
+
+	const connName = "prefix"
+	ConnectByConf(connName, &ConnConf{Servers: []string{addr}, KeyPrefix: "test:"})
+	RegisterKeyRouteWithOpts("prefix.order", connName, &KeyRouteOpts{Prefix: "order:"})
+
+	if prefix := KeyPrefix("prefix.order"); prefix != "test:order:" {
+		t.Fatalf("unexpected prefix %q", prefix)
+	}
+
+	if err := Set(NewKey("prefix.order", "%d", 1), "v", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	err := ScanKeys("prefix.order", "", 10, func(key *Key) error {
+		keys = append(keys, key.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 2 || keys[0] != "1" || keys[1] != "2" {
+		t.Fatalf("expect stripped keys, got %v", keys)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if seen[0] != "test:order:1" || seen[1] != "test:order:*" {
+		t.Fatalf("expect prefixed key and match, got %v", seen)
+	}
+}
+
+func TestKeyRoutePattern(t *testing.T) {
+	if err := RegisterKeyRoutePattern("rule.[", "bad"); err == nil {
+		t.Fatal("expect bad pattern error")
+	}
+
+	RegisterKeyRoute("rule.user.exact", "exact")
+	if err := RegisterKeyRoutePattern("rule.*", "rule"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterKeyRoutePattern("rule.user.*", "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"rule.user.exact": "exact",
+		"rule.user.1":     "user",
+		"rule.order":      "rule",
+	}
+	for route, connName := range cases {
+		if got, err := RouteConnName(route); err != nil || got != connName {
+			t.Fatalf("route %s expect %s, got %s %v", route, connName, got, err)
+		}
+	}
+
+	if _, err := RouteConnName("unknown.route"); !errors.Is(err, ErrRedisKeyRouteNotRegistered) {
+		t.Fatalf("expect not registered, got %v", err)
+	}
+
+	SetFallbackKeyRoute("fallback", nil)
+	defer SetFallbackKeyRoute("", nil)
+
+	match, err := ResolveKeyRoute("unknown.route")
+	if err != nil || match.ConnName != "fallback" || match.Kind != KeyRouteMatchFallback {
+		t.Fatalf("expect fallback, got %+v %v", match, err)
+	}
+
+	match, err = ResolveKeyRoute("rule.user.1")
+	if err != nil || match.Kind != KeyRouteMatchPattern || match.Pattern != "rule.user.*" {
+		t.Fatalf("expect pattern match, got %+v %v", match, err)
+	}
+}
@@ -0,0 +1,182 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+var (
+	ErrScriptNotRegistered    = errors.New("redis script not registered")
+	ErrScriptKeysRequired     = errors.New("redis script requires at least one key for routing")
+	ErrScriptKeyRouteMismatch = errors.New("redis script keys must share one route")
+	ErrScriptCrossSlot        = errors.New("redis script keys must share one cluster slot")
+	ErrScriptCrossShard       = errors.New("redis script keys must share one shard")
+)
+
+// Script 按名称注册的lua脚本, 每个连接首次执行前通过SCRIPT LOAD加载,
+// 之后使用EVALSHA调用, 服务端脚本缓存丢失(NOSCRIPT)时自动退回EVAL
+type Script struct {
+	name   string
+	src    string
+	hash   string
+	loaded sync.Map // 已加载脚本的连接池(集群模式下为节点)
+}
+
+type scriptLoadedKey struct {
+	pool RedisPool
+	addr string
+}
+
+func RegisterScript(name string, src string) *Script {
+	return defaultClient.RegisterScript(name, src)
+}
+
+// RegisterScript 在c中按名称注册脚本, 同名脚本会被替换
+func (c *Client) RegisterScript(name string, src string) *Script {
+	script := &Script{
+		name: name,
+		src:  src,
+		hash: redis.NewScript(0, src).Hash(),
+	}
+	c.scripts.Store(name, script)
+	return script
+}
+
+func GetScript(name string) (*Script, error) {
+	return defaultClient.GetScript(name)
+}
+
+func (c *Client) GetScript(name string) (*Script, error) {
+	script, ok := c.scripts.Load(name)
+	if !ok {
+		return nil, ErrScriptNotRegistered
+	}
+	return script.(*Script), nil
+}
+
+func (s *Script) Name() string {
+	return s.name
+}
+
+func (s *Script) Hash() string {
+	return s.hash
+}
+
+func (s *Script) Do(keys []*Key, args ...any) (any, error) {
+	return s.DoContext(context.Background(), keys, args...)
+}
+
+// DoContext 执行脚本, keys作为KEYS传入并决定路由, 所有key必须属于同一路由, 集群模式下还必须属于同一槽位, 分片路由下必须属于同一分片
+func (s *Script) DoContext(ctx context.Context, keys []*Key, args ...any) (res any, err error) {
+	if len(keys) == 0 {
+		return nil, ErrScriptKeysRequired
+	}
+
+	if onCmdDone := keys[0].Client().cmdDoneHook(); onCmdDone != nil {
+		start := time.Now()
+		defer func() {
+			onCmdDone("EvalScript", nil, time.Since(start), "EVALSHA", keys[0], err, args...)
+		}()
+	}
+
+	connName, err := KeyConnName(keys[0])
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := keys[0].Client().GetConnPool(connName)
+	if err != nil {
+		return nil, err
+	}
+
+	keyStrs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.Route != keys[0].Route || key.Client() != keys[0].Client() {
+			return nil, ErrScriptKeyRouteMismatch
+		}
+		// 分片路由下所有key必须落在同一分片, 可以通过{hashtag}保证
+		if keyConnName, _ := KeyConnName(key); keyConnName != connName {
+			return nil, ErrScriptCrossShard
+		}
+		keyStrs = append(keyStrs, key.RedisKey())
+	}
+
+	conn, loadedKey, err := scriptConn(ctx, pool, keyStrs)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err = conn.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.loaded.Load(loadedKey); !ok {
+		if _, err = doContext(ctx, conn, "SCRIPT", "LOAD", s.src); err != nil {
+			return nil, err
+		}
+		s.loaded.Store(loadedKey, struct{}{})
+	}
+
+	evalArgs := make([]any, 0, 2+len(keyStrs)+len(args))
+	evalArgs = append(evalArgs, s.hash, len(keyStrs))
+	for _, key := range keyStrs {
+		evalArgs = append(evalArgs, key)
+	}
+	evalArgs = append(evalArgs, args...)
+
+	res, err = doContext(ctx, conn, "EVALSHA", evalArgs...)
+	if isNoScriptErr(err) {
+		evalArgs[0] = s.src
+		res, err = doContext(ctx, conn, "EVAL", evalArgs...)
+	}
+
+	return res, err
+}
+
+// scriptConn 集群模式下EVALSHA的第一个参数不是key, RetryConn无法据此定位节点, 需要按KEYS绑定节点
+func scriptConn(ctx context.Context, pool RedisPool, keys []string) (redis.Conn, scriptLoadedKey, error) {
+	cluster, ok := pool.(*RedisCluster)
+	if !ok {
+		conn, err := getPoolConnContext(ctx, pool)
+		if err != nil {
+			return nil, scriptLoadedKey{}, err
+		}
+		return conn, scriptLoadedKey{pool: pool}, nil
+	}
+
+	slot := redisc.Slot(keys[0])
+	for _, key := range keys[1:] {
+		if redisc.Slot(key) != slot {
+			return nil, scriptLoadedKey{}, ErrScriptCrossSlot
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, scriptLoadedKey{}, err
+	}
+
+	addr, err := cluster.SlotAddr(slot)
+	if err != nil {
+		return nil, scriptLoadedKey{}, err
+	}
+
+	conn := cluster.Cluster.Get()
+	if err = redisc.BindConn(conn, keys...); err != nil {
+		conn.Close()
+		return nil, scriptLoadedKey{}, err
+	}
+
+	return conn, scriptLoadedKey{pool: pool, addr: addr}, nil
+}
+
+func isNoScriptErr(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "NOSCRIPT")
+}
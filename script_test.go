@@ -0,0 +1,48 @@
+package routeredis
+
+import (
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestScriptFallbackToEval(t *testing.T) {
+	var cmds []string
+	addr := newFakeRedis(t, func(args []string) any {
+		cmds = append(cmds, args[0])
+		switch args[0] {
+		case "SCRIPT":
+			return "sha"
+		case "EVALSHA":
+			return redis.Error("NOSCRIPT No matching script. Please use EVAL.")
+		case "EVAL":
+			return []any{args[3], args[4]}
+		}
+		return redis.Error("ERR unknown command")
+	})
+
+	const route = "script_fallback"
+	ConnectByConf(route, &ConnConf{Servers: []string{addr}})
+	RegisterKeyRoute(route, route)
+
+	script := RegisterScript("echo", "return {KEYS[1], ARGV[1]}")
+	if got, _ := GetScript("echo"); got != script {
+		t.Fatal("expect registered script")
+	}
+
+	res, err := redis.Strings(script.Do([]*Key{NewKey(route, "k")}, "v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0] != "k" || res[1] != "v" {
+		t.Errorf("unexpected reply %v", res)
+	}
+
+	if len(cmds) != 3 || cmds[0] != "SCRIPT" || cmds[1] != "EVALSHA" || cmds[2] != "EVAL" {
+		t.Errorf("unexpected command sequence %v", cmds)
+	}
+
+	if _, err = script.Do([]*Key{NewKey(route, "a"), NewKey("other", "b")}); err != ErrScriptKeyRouteMismatch {
+		t.Errorf("expect route mismatch, got %v", err)
+	}
+}
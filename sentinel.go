@@ -0,0 +1,495 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	ErrNoSentinelAvailable         = errors.New("no sentinel is available")
+	ErrSentinelMasterNotFound      = errors.New("sentinel master not found")
+	ErrSentinelMasterNameRequired  = errors.New("sentinel master name required")
+	errSentinelConnTopologyChanged = errors.New("sentinel topology changed, conn discarded")
+)
+
+const sentinelTimeout = time.Second
+
+type Sentinel struct {
+	MasterName  string
+	Password    string
+	DialOptions []redis.DialOption // 附加的拨号参数, 例如TLS
+	mu          sync.Mutex
+	addrs       []string
+}
+
+func NewSentinel(masterName, password string, addrs []string) *Sentinel {
+	return &Sentinel{
+		MasterName: masterName,
+		Password:   password,
+		addrs:      append([]string(nil), addrs...),
+	}
+}
+
+func (s *Sentinel) Addrs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.addrs...)
+}
+
+// MasterAddr 依次询问哨兵当前的主库地址, 成功应答的哨兵会被提到最前面优先使用
+func (s *Sentinel) MasterAddr() (string, error) {
+	lastErr := ErrNoSentinelAvailable
+	for _, addr := range s.Addrs() {
+		masterAddr, err := s.masterAddrFrom(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		s.promote(addr)
+		return masterAddr, nil
+	}
+	return "", lastErr
+}
+
+// ReplicaAddrs 返回哨兵认为健康的从库地址
+func (s *Sentinel) ReplicaAddrs() ([]string, error) {
+	lastErr := ErrNoSentinelAvailable
+	for _, addr := range s.Addrs() {
+		replicaAddrs, err := s.replicaAddrsFrom(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		s.promote(addr)
+		return replicaAddrs, nil
+	}
+	return nil, lastErr
+}
+
+func (s *Sentinel) masterAddrFrom(sentinelAddr string) (string, error) {
+	conn, err := s.dial(sentinelAddr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	res, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", s.MasterName))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return "", ErrSentinelMasterNotFound
+		}
+		return "", err
+	}
+
+	if len(res) != 2 {
+		return "", ErrSentinelMasterNotFound
+	}
+
+	return net.JoinHostPort(res[0], res[1]), nil
+}
+
+func (s *Sentinel) replicaAddrsFrom(sentinelAddr string) ([]string, error) {
+	conn, err := s.dial(sentinelAddr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	res, err := redis.Values(conn.Do("SENTINEL", "replicas", s.MasterName))
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	for _, item := range res {
+		replica, err := redis.StringMap(item, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if isSentinelReplicaDown(replica["flags"]) {
+			continue
+		}
+
+		addrs = append(addrs, net.JoinHostPort(replica["ip"], replica["port"]))
+	}
+
+	return addrs, nil
+}
+
+func isSentinelReplicaDown(flags string) bool {
+	for _, flag := range strings.Split(flags, ",") {
+		switch flag {
+		case "s_down", "o_down", "disconnected":
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Sentinel) promote(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, a := range s.addrs {
+		if a == addr {
+			copy(s.addrs[1:i+1], s.addrs[:i])
+			s.addrs[0] = addr
+			return
+		}
+	}
+}
+
+func (s *Sentinel) dial(addr string) (redis.Conn, error) {
+	opts := []redis.DialOption{
+		redis.DialConnectTimeout(sentinelTimeout),
+		redis.DialReadTimeout(sentinelTimeout),
+		redis.DialWriteTimeout(sentinelTimeout),
+	}
+	if s.Password != "" {
+		opts = append(opts, redis.DialPassword(s.Password))
+	}
+	opts = append(opts, s.DialOptions...)
+	return redis.Dial("tcp", addr, opts...)
+}
+
+func ConnectSentinelByConf(connName string, conf *ConnConf) error {
+	return defaultClient.ConnectSentinelByConf(connName, conf)
+}
+
+func (c *Client) ConnectSentinelByConf(connName string, conf *ConnConf) error {
+	if conf.SentinelMasterName == "" {
+		return ErrSentinelMasterNameRequired
+	}
+
+	pool, err := NewSentinelPool(conf)
+	if err != nil {
+		return err
+	}
+
+	if _, err = pool.sentinel.MasterAddr(); err != nil {
+		return err
+	}
+
+	c.SetConnKeyPrefix(connName, conf.KeyPrefix)
+	c.SetConnRetryPolicy(connName, conf.Retry)
+	c.Connect(connName, pool)
+
+	return nil
+}
+
+func ConnectDefaultSentinelByConf(conf *ConnConf) error {
+	return ConnectSentinelByConf(DefaultConnName, conf)
+}
+
+var _ ContextRedisPool = (*SentinelPool)(nil)
+
+// SentinelPool 通过哨兵发现主库的连接池, 连接遇到READONLY或者网络错误时视为发生了故障转移,
+// 此后旧拓扑下建立的连接都会被丢弃, 新连接重新向哨兵询问主库地址
+type SentinelPool struct {
+	dialOpts   []redis.DialOption
+	sentinel   *Sentinel
+	master     *redis.Pool
+	replica    *redis.Pool
+	topoGen    atomic.Uint64
+	replicaIdx atomic.Uint64
+}
+
+func NewSentinelPool(conf *ConnConf) (*SentinelPool, error) {
+	dialOpts, err := conf.dialOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	sentinelDialOpts, err := conf.tlsDialOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &SentinelPool{
+		dialOpts: dialOpts,
+		sentinel: NewSentinel(conf.SentinelMasterName, conf.SentinelPassword, conf.SentinelAddrs),
+	}
+	p.sentinel.DialOptions = sentinelDialOpts
+
+	p.master = newRedisPool(conf)
+	p.master.Dial = p.dialMaster
+	p.master.TestOnBorrow = p.testOnBorrow
+
+	if conf.ReadFromReplicas {
+		p.replica = newRedisPool(conf)
+		p.replica.Dial = p.dialReplica
+		p.replica.TestOnBorrow = p.testOnBorrow
+	}
+
+	return p, nil
+}
+
+func (p *SentinelPool) Sentinel() *Sentinel {
+	return p.sentinel
+}
+
+func (p *SentinelPool) Get() redis.Conn {
+	if p.replica == nil {
+		return p.master.Get()
+	}
+	return &sentinelConn{pool: p, ctx: context.Background()}
+}
+
+// GetContext ctx经过WithReadFromPrimary时直接返回主库连接
+func (p *SentinelPool) GetContext(ctx context.Context) (redis.Conn, error) {
+	if p.replica == nil || isReadFromPrimary(ctx) {
+		return p.master.GetContext(ctx)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return &sentinelConn{pool: p, ctx: ctx}, nil
+}
+
+func (p *SentinelPool) Close() error {
+	err := p.master.Close()
+	if p.replica != nil {
+		if replicaErr := p.replica.Close(); err == nil {
+			err = replicaErr
+		}
+	}
+	return err
+}
+
+func (p *SentinelPool) dialMaster() (redis.Conn, error) {
+	gen := p.topoGen.Load()
+
+	addr, err := p.sentinel.MasterAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := redis.Dial("tcp", addr, p.dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &failoverConn{Conn: conn, pool: p, gen: gen}, nil
+}
+
+func (p *SentinelPool) dialReplica() (redis.Conn, error) {
+	addrs, err := p.sentinel.ReplicaAddrs()
+	if err != nil || len(addrs) == 0 {
+		return p.dialMaster()
+	}
+
+	gen := p.topoGen.Load()
+	addr := addrs[p.replicaIdx.Add(1)%uint64(len(addrs))]
+	conn, err := redis.Dial("tcp", addr, p.dialOpts...)
+	if err != nil {
+		return p.dialMaster()
+	}
+
+	return &failoverConn{Conn: conn, pool: p, gen: gen}, nil
+}
+
+func (p *SentinelPool) testOnBorrow(c redis.Conn, t time.Time) error {
+	if fc, ok := c.(*failoverConn); ok && fc.gen != p.topoGen.Load() {
+		return errSentinelConnTopologyChanged
+	}
+	return redisOnBorrow(c, t)
+}
+
+var _ redis.ConnWithContext = (*failoverConn)(nil)
+
+type failoverConn struct {
+	redis.Conn
+	pool   *SentinelPool
+	gen    uint64
+	broken atomic.Bool
+}
+
+func (c *failoverConn) Err() error {
+	if c.broken.Load() {
+		return errSentinelConnTopologyChanged
+	}
+	return c.Conn.Err()
+}
+
+func (c *failoverConn) Do(cmd string, args ...any) (any, error) {
+	reply, err := c.Conn.Do(cmd, args...)
+	c.checkFailover(context.Background(), err)
+	return reply, err
+}
+
+func (c *failoverConn) DoContext(ctx context.Context, cmd string, args ...any) (any, error) {
+	reply, err := redis.DoContext(c.Conn, ctx, cmd, args...)
+	c.checkFailover(ctx, err)
+	return reply, err
+}
+
+func (c *failoverConn) DoWithTimeout(timeout time.Duration, cmd string, args ...any) (any, error) {
+	reply, err := redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+	c.checkFailover(context.Background(), err)
+	return reply, err
+}
+
+func (c *failoverConn) Receive() (any, error) {
+	reply, err := c.Conn.Receive()
+	c.checkFailover(context.Background(), err)
+	return reply, err
+}
+
+func (c *failoverConn) ReceiveContext(ctx context.Context) (any, error) {
+	reply, err := redis.ReceiveContext(c.Conn, ctx)
+	c.checkFailover(ctx, err)
+	return reply, err
+}
+
+func (c *failoverConn) ReceiveWithTimeout(timeout time.Duration) (any, error) {
+	reply, err := redis.ReceiveWithTimeout(c.Conn, timeout)
+	c.checkFailover(context.Background(), err)
+	return reply, err
+}
+
+// checkFailover READONLY或者网络错误时视为发生了故障转移. 调用方的ctx取消或者超时时redigo同样会把连接标记为不可用,
+// 这种连接归还后由连接池丢弃即可, 不能作为故障转移丢弃全部主库连接.
+// redigo按ctx的截止时间设置读超时, 读超时可能先于ctx返回, 因此ctx带截止时间时超时错误同样归于调用方
+func (c *failoverConn) checkFailover(ctx context.Context, err error) {
+	if err == nil || ctx.Err() != nil || isContextErr(err) || isContextErr(c.Conn.Err()) {
+		return
+	}
+	if _, ok := ctx.Deadline(); ok && isTimeoutErr(err) {
+		return
+	}
+
+	if !isReadOnlyErr(err) && c.Conn.Err() == nil {
+		return
+	}
+
+	c.broken.Store(true)
+	c.pool.topoGen.CompareAndSwap(c.gen, c.gen+1)
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func isTimeoutErr(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isReadOnlyErr(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "READONLY")
+}
+
+var _ redis.ConnWithContext = (*sentinelConn)(nil)
+
+// sentinelConn 开启ReadFromReplicas时使用, 只读命令发往从库, 其余命令以及Send/Receive发往主库,
+// 从库不可用或者ctx经过WithReadFromPrimary时发往主库执行
+type sentinelConn struct {
+	pool    *SentinelPool
+	ctx     context.Context
+	master  redis.Conn
+	replica redis.Conn
+}
+
+func (c *sentinelConn) masterConn() redis.Conn {
+	if c.master == nil {
+		conn, err := c.pool.master.GetContext(c.ctx)
+		if err != nil {
+			conn = NewErrConn(err)
+		}
+		c.master = conn
+	}
+	return c.master
+}
+
+func (c *sentinelConn) replicaConn() redis.Conn {
+	if c.replica == nil {
+		conn, err := c.pool.replica.GetContext(c.ctx)
+		if err != nil {
+			conn = NewErrConn(err)
+		}
+		c.replica = conn
+	}
+	return c.replica
+}
+
+func (c *sentinelConn) do(ctx context.Context, cmd string, fn func(conn redis.Conn) (any, error)) (any, error) {
+	if cmd == "" || !IsReadOnlyCmd(cmd) || isReadFromPrimary(ctx) {
+		return fn(c.masterConn())
+	}
+
+	replica := c.replicaConn()
+	if replica.Err() == nil {
+		reply, err := fn(replica)
+		if replica.Err() == nil {
+			return reply, err
+		}
+	}
+
+	return fn(c.masterConn())
+}
+
+func (c *sentinelConn) Do(cmd string, args ...any) (any, error) {
+	return c.do(c.ctx, cmd, func(conn redis.Conn) (any, error) {
+		return conn.Do(cmd, args...)
+	})
+}
+
+func (c *sentinelConn) DoContext(ctx context.Context, cmd string, args ...any) (any, error) {
+	return c.do(ctx, cmd, func(conn redis.Conn) (any, error) {
+		return doContext(ctx, conn, cmd, args...)
+	})
+}
+
+func (c *sentinelConn) DoWithTimeout(timeout time.Duration, cmd string, args ...any) (any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.DoContext(ctx, cmd, args...)
+}
+
+func (c *sentinelConn) Send(cmd string, args ...any) error {
+	return c.masterConn().Send(cmd, args...)
+}
+
+func (c *sentinelConn) Flush() error {
+	return c.masterConn().Flush()
+}
+
+func (c *sentinelConn) Receive() (any, error) {
+	return c.masterConn().Receive()
+}
+
+func (c *sentinelConn) ReceiveContext(ctx context.Context) (any, error) {
+	return redis.ReceiveContext(c.masterConn(), ctx)
+}
+
+func (c *sentinelConn) ReceiveWithTimeout(timeout time.Duration) (any, error) {
+	return redis.ReceiveWithTimeout(c.masterConn(), timeout)
+}
+
+func (c *sentinelConn) Err() error {
+	if c.master == nil {
+		return nil
+	}
+	return c.master.Err()
+}
+
+func (c *sentinelConn) Close() error {
+	var err error
+	if c.replica != nil {
+		_ = c.replica.Close()
+	}
+	if c.master != nil {
+		err = c.master.Close()
+	}
+	return err
+}
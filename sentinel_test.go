@@ -0,0 +1,194 @@
+package routeredis
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSentinelFailover(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		masterAddr string
+	)
+
+	oldMaster := newFakeRedis(t, func(args []string) any {
+		if args[0] == "SET" {
+			return redis.Error("READONLY You can't write against a read only replica.")
+		}
+		return "old"
+	})
+	newMaster := newFakeRedis(t, func(args []string) any {
+		if args[0] == "SET" {
+			return "OK"
+		}
+		return "new"
+	})
+	masterAddr = oldMaster
+
+	sentinel := newFakeRedis(t, func(args []string) any {
+		if len(args) == 3 && args[0] == "SENTINEL" && args[1] == "get-master-addr-by-name" && args[2] == "mymaster" {
+			mu.Lock()
+			defer mu.Unlock()
+			host, port, _ := net.SplitHostPort(masterAddr)
+			return []any{host, port}
+		}
+		return redis.Error("ERR unknown command")
+	})
+
+	const connName = "sentinel_failover"
+	err := ConnectSentinelByConf(connName, &ConnConf{
+		SentinelAddrs:      []string{"127.0.0.1:1", sentinel},
+		SentinelMasterName: "mymaster",
+		IdleCount:          4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterKeyRoute(connName, connName)
+	key := NewKey(connName, "foo")
+
+	res, err := redis.String(DoCmdWithTTL(nil, "GET", key))
+	if err != nil || res != "old" {
+		t.Fatalf("expect old master, got %q %v", res, err)
+	}
+
+	mu.Lock()
+	masterAddr = newMaster
+	mu.Unlock()
+
+	if _, err = DoCmdWithTTL(nil, "SET", key, "bar"); err == nil {
+		t.Fatal("expect READONLY error from demoted master")
+	}
+
+	if _, err = DoCmdWithTTL(nil, "SET", key, "bar"); err != nil {
+		t.Fatalf("expect write to new master, got %v", err)
+	}
+
+	res, err = redis.String(DoCmdWithTTL(nil, "GET", key))
+	if err != nil || res != "new" {
+		t.Fatalf("expect new master, got %q %v", res, err)
+	}
+}
+
+func TestSentinelReadFromReplicas(t *testing.T) {
+	master := newFakeRedis(t, func(args []string) any {
+		if args[0] == "EXEC" {
+			return []any{"OK"}
+		}
+		return "master"
+	})
+	replica := newFakeRedis(t, func(args []string) any {
+		return "replica"
+	})
+	sentinel := newFakeRedis(t, func(args []string) any {
+		switch {
+		case len(args) == 3 && args[1] == "get-master-addr-by-name":
+			host, port, _ := net.SplitHostPort(master)
+			return []any{host, port}
+		case len(args) == 3 && args[1] == "replicas":
+			host, port, _ := net.SplitHostPort(replica)
+			return []any{
+				[]any{"ip", host, "port", port, "flags", "slave"},
+				[]any{"ip", "127.0.0.1", "port", "1", "flags", "slave,s_down"},
+			}
+		}
+		return redis.Error("ERR unknown command")
+	})
+
+	const connName = "sentinel_replicas"
+	err := ConnectSentinelByConf(connName, &ConnConf{
+		SentinelAddrs:      []string{sentinel},
+		SentinelMasterName: "mymaster",
+		ReadFromReplicas:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterKeyRoute(connName, connName)
+	key := NewKey(connName, "foo")
+
+	res, err := redis.String(DoCmdWithTTL(nil, "GET", key))
+	if err != nil || res != "replica" {
+		t.Fatalf("expect read from replica, got %q %v", res, err)
+	}
+
+	res, err = redis.String(DoCmdWithTTL(nil, "SET", key, "bar"))
+	if err != nil || res != "master" {
+		t.Fatalf("expect write to master, got %q %v", res, err)
+	}
+
+	res, err = redis.String(DoCmdWithTTLContext(WithReadFromPrimary(context.Background()), nil, "GET", key))
+	if err != nil || res != "master" {
+		t.Fatalf("expect read from master with WithReadFromPrimary, got %q %v", res, err)
+	}
+	pool, err := GetConnPool(connName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := pool.Get()
+	res, err = redis.String(redis.DoContext(conn, WithReadFromPrimary(context.Background()), "GET", key.RedisKey()))
+	conn.Close()
+	if err != nil || res != "master" {
+		t.Fatalf("expect conn read from master with WithReadFromPrimary, got %q %v", res, err)
+	}
+
+	// 事务中的读取必须和WATCH在同一条主库连接上
+	_, err = NewTx(connName).Exec(func(tx *Tx) error {
+		if err := tx.Watch(key); err != nil {
+			return err
+		}
+		if res, err = redis.String(tx.Do("GET", key)); err != nil {
+			return err
+		}
+		return tx.Queue(nil, "SET", key, res)
+	})
+	if err != nil || res != "master" {
+		t.Fatalf("expect tx read from master, got %q %v", res, err)
+	}
+}
+
+func TestSentinelCtxTimeoutNotFailover(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() {
+		close(release)
+	})
+	master := newFakeRedis(t, func(args []string) any {
+		if args[0] == "BLPOP" {
+			<-release
+		}
+		return "OK"
+	})
+	sentinel := newFakeRedis(t, func(args []string) any {
+		if len(args) == 3 && args[1] == "get-master-addr-by-name" {
+			host, port, _ := net.SplitHostPort(master)
+			return []any{host, port}
+		}
+		return redis.Error("ERR unknown command")
+	})
+
+	c := NewClient()
+	if err := c.ConnectSentinelByConf("main", &ConnConf{SentinelAddrs: []string{sentinel}, SentinelMasterName: "mymaster"}); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("sentinel.ctx", "main")
+	pool, err := c.GetConnPool("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := pool.(*SentinelPool).topoGen.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = DoCmdWithTTLContext(ctx, nil, "BLPOP", c.NewKey("sentinel.ctx", "k"), 0); err == nil {
+		t.Fatal("expect ctx timeout")
+	}
+
+	if got := pool.(*SentinelPool).topoGen.Load(); got != gen {
+		t.Fatalf("expect ctx timeout not treated as failover, topo gen %d -> %d", gen, got)
+	}
+}
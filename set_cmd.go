@@ -1,21 +1,21 @@
 package routeredis
 
 import (
+	"context"
 	"github.com/gomodule/redigo/redis"
-	jsoniter "github.com/json-iterator/go"
 )
 
 func AsyncSadd(key *Key, data any, ttl int64) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return AsyncSaddCtx(context.Background(), key, data, ttl)
+}
+
+func AsyncSaddCtx(ctx context.Context, key *Key, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	_, err := DoCmdWithTTL(NewAsyncSecTTL(ttl), "SADD", key, str)
+	_, err = DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "SADD", key, str)
 	if err != nil {
 		return err
 	}
@@ -24,7 +24,16 @@ func AsyncSadd(key *Key, data any, ttl int64) error {
 }
 
 func Sismember(key *Key, value any) (bool, error) {
-	res, err := redis.Int64(DoCmdWithTTL(nil, "SISMEMBER", key, value))
+	return SismemberCtx(context.Background(), key, value)
+}
+
+func SismemberCtx(ctx context.Context, key *Key, value any) (bool, error) {
+	str, err := encodeValue(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := redis.Int64(DoCmdWithTTLContext(ctx, nil, "SISMEMBER", key, str))
 	if err != nil {
 		return false, err
 	}
@@ -32,27 +41,58 @@ func Sismember(key *Key, value any) (bool, error) {
 }
 
 func Scard(key *Key) (int, error) {
-	return redis.Int(DoCmdWithTTL(nil, "SCARD", key))
+	return ScardCtx(context.Background(), key)
+}
+
+func ScardCtx(ctx context.Context, key *Key) (int, error) {
+	return redis.Int(DoCmdWithTTLContext(ctx, nil, "SCARD", key))
 }
 
 func SpopInt64(key *Key) (int64, error) {
-	return redis.Int64(DoCmdWithTTL(nil, "SPOP", key, 1))
+	return SpopInt64Ctx(context.Background(), key)
+}
+
+func SpopInt64Ctx(ctx context.Context, key *Key) (int64, error) {
+	return redis.Int64(DoCmdWithTTLContext(ctx, nil, "SPOP", key, 1))
 }
 
 func Spop(key *Key) (string, error) {
-	return redis.String(DoCmdWithTTL(nil, "SPOP", key, 1))
+	return SpopCtx(context.Background(), key)
 }
 
+func SpopCtx(ctx context.Context, key *Key) (string, error) {
+	return redis.String(DoCmdWithTTLContext(ctx, nil, "SPOP", key, 1))
+}
+
+// Deprecated: 使用SmembersAs[int64]
 func SmembersInt64s(key *Key) ([]int64, error) {
-	return redis.Int64s(DoCmdWithTTL(nil, "SMEMBERS", key))
+	return SmembersInt64sCtx(context.Background(), key)
+}
+
+// Deprecated: 使用SmembersAsCtx[int64]
+func SmembersInt64sCtx(ctx context.Context, key *Key) ([]int64, error) {
+	return SmembersAsCtx[int64](ctx, key)
 }
 
 func Smembers(key *Key) ([]string, error) {
-	return redis.Strings(DoCmdWithTTL(nil, "SMEMBERS", key))
+	return SmembersCtx(context.Background(), key)
+}
+
+func SmembersCtx(ctx context.Context, key *Key) ([]string, error) {
+	return redis.Strings(DoCmdWithTTLContext(ctx, nil, "SMEMBERS", key))
 }
 
 func Srem(key *Key, value any) (bool, error) {
-	res, err := redis.Int64(DoCmdWithTTL(nil, "SREM", key, value))
+	return SremCtx(context.Background(), key, value)
+}
+
+func SremCtx(ctx context.Context, key *Key, value any) (bool, error) {
+	str, err := encodeValue(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := redis.Int64(DoCmdWithTTLContext(ctx, nil, "SREM", key, str))
 	if err != nil {
 		return false, err
 	}
@@ -60,7 +100,11 @@ func Srem(key *Key, value any) (bool, error) {
 }
 
 func Sscan(key *Key, cursor, count int64) (int64, []string, error) {
-	res, err := redis.Values(DoCmdWithTTL(nil, "SSCAN", key, cursor, "COUNT", count))
+	return SscanCtx(context.Background(), key, cursor, count)
+}
+
+func SscanCtx(ctx context.Context, key *Key, cursor, count int64) (int64, []string, error) {
+	res, err := redis.Values(DoCmdWithTTLContext(ctx, nil, "SSCAN", key, cursor, "COUNT", count))
 	if err != nil {
 		return 0, nil, err
 	}
@@ -0,0 +1,223 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrKeyRouteSharded    = errors.New("redis key route is sharded, conn depends on key")
+	ErrKeyRouteNotSharded = errors.New("redis key route is not sharded")
+	ErrShardExists        = errors.New("redis shard already exists in key route")
+	ErrShardConnRequired  = errors.New("redis sharded key route requires non-empty conn names")
+)
+
+const DefaultShardVirtualNodes = 160
+
+type ShardedKeyRouteOpts struct {
+	Prefix       string
+	VirtualNodes int // 每个连接在哈希环上的虚拟节点数, <=0时取DefaultShardVirtualNodes
+	TTL          int64
+}
+
+// RegisterShardedKeyRoute 注册分片路由, 按Key.Key的一致性哈希在connNames中选择连接,
+// key中包含{hashtag}时只对hashtag哈希, 与集群模式的规则一致, 便于把相关的key放在同一分片
+func RegisterShardedKeyRoute(route string, connNames ...string) error {
+	return defaultClient.RegisterShardedKeyRouteWithOpts(route, nil, connNames...)
+}
+
+func RegisterShardedKeyRouteWithOpts(route string, opts *ShardedKeyRouteOpts, connNames ...string) error {
+	return defaultClient.RegisterShardedKeyRouteWithOpts(route, opts, connNames...)
+}
+
+func (c *Client) RegisterShardedKeyRoute(route string, connNames ...string) error {
+	return c.RegisterShardedKeyRouteWithOpts(route, nil, connNames...)
+}
+
+// RegisterShardedKeyRouteWithOpts connNames为空或者包含空连接名时返回ErrShardConnRequired
+func (c *Client) RegisterShardedKeyRouteWithOpts(route string, opts *ShardedKeyRouteOpts, connNames ...string) error {
+	if len(connNames) == 0 || slices.Contains(connNames, "") {
+		return ErrShardConnRequired
+	}
+
+	r := &keyRoute{}
+	vnodes := 0
+	if opts != nil {
+		r.prefix, r.ttl = opts.Prefix, opts.TTL
+		vnodes = opts.VirtualNodes
+	}
+	r.ring = newHashRing(connNames, vnodes)
+	c.keyRoutes.Store(route, r)
+
+	return nil
+}
+
+func RouteShards(route string) ([]string, error) {
+	return defaultClient.RouteShards(route)
+}
+
+// RouteShards 分片路由的全部分片连接名
+func (c *Client) RouteShards(route string) ([]string, error) {
+	r, ok := c.lookupKeyRoute(route)
+	if !ok {
+		return nil, ErrRedisKeyRouteNotRegistered
+	}
+	if r.ring == nil {
+		return nil, ErrKeyRouteNotSharded
+	}
+	return slices.Clone(r.ring.connNames), nil
+}
+
+func AddKeyRouteShard(route string, connName string) error {
+	return defaultClient.AddKeyRouteShard(route, connName)
+}
+
+// AddKeyRouteShard 向分片路由加入新的连接, 约1/(n+1)的key会改为映射到新分片,
+// 加入前可以用PlanAddKeyRouteShard查看需要迁移的key
+func (c *Client) AddKeyRouteShard(route string, connName string) error {
+	r, next, err := c.nextShardedKeyRoute(route, connName)
+	if err != nil {
+		return err
+	}
+	if !c.keyRoutes.CompareAndSwap(route, r, next) {
+		return c.AddKeyRouteShard(route, connName)
+	}
+	return nil
+}
+
+type ShardMove struct {
+	Key  *Key
+	From string
+	To   string
+}
+
+func PlanAddKeyRouteShard(ctx context.Context, route string, connName string, match string) ([]*ShardMove, error) {
+	return defaultClient.PlanAddKeyRouteShard(ctx, route, connName, match)
+}
+
+// PlanAddKeyRouteShard 扫描各分片中匹配match的key, 返回加入connName后映射会改变的key, 不修改路由
+func (c *Client) PlanAddKeyRouteShard(ctx context.Context, route string, connName string, match string) ([]*ShardMove, error) {
+	r, next, err := c.nextShardedKeyRoute(route, connName)
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []*ShardMove
+	err = c.scanRouteKeys(ctx, route, r, match, 0, func(key *Key) error {
+		from, to := r.ring.get(key.Key), next.ring.get(key.Key)
+		if from != to {
+			moves = append(moves, &ShardMove{Key: key, From: from, To: to})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return moves, nil
+}
+
+func (c *Client) nextShardedKeyRoute(route string, connName string) (*keyRoute, *keyRoute, error) {
+	if connName == "" {
+		return nil, nil, ErrShardConnRequired
+	}
+
+	v, ok := c.keyRoutes.Load(route)
+	if !ok {
+		return nil, nil, ErrRedisKeyRouteNotRegistered
+	}
+
+	r := v.(*keyRoute)
+	if r.ring == nil {
+		return nil, nil, ErrKeyRouteNotSharded
+	}
+
+	if slices.Contains(r.ring.connNames, connName) {
+		return nil, nil, ErrShardExists
+	}
+
+	next := *r
+	next.ring = newHashRing(append(slices.Clone(r.ring.connNames), connName), r.ring.vnodes)
+
+	return r, &next, nil
+}
+
+type hashRing struct {
+	connNames []string
+	vnodes    int
+	hashes    []uint64
+	owners    map[uint64]string
+}
+
+func newHashRing(connNames []string, vnodes int) *hashRing {
+	if vnodes <= 0 {
+		vnodes = DefaultShardVirtualNodes
+	}
+
+	ring := &hashRing{
+		connNames: slices.Clone(connNames),
+		vnodes:    vnodes,
+		owners:    make(map[uint64]string, len(connNames)*vnodes),
+	}
+	for _, connName := range connNames {
+		for i := 0; i < vnodes; i++ {
+			h := hashShardKey(connName + "#" + strconv.Itoa(i))
+			// 哈希冲突时保留先加入的连接
+			if _, ok := ring.owners[h]; ok {
+				continue
+			}
+			ring.owners[h] = connName
+			ring.hashes = append(ring.hashes, h)
+		}
+	}
+	slices.Sort(ring.hashes)
+
+	return ring
+}
+
+func (r *hashRing) get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := hashShardKey(shardHashTag(key))
+	i := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= h
+	})
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.owners[r.hashes[i]]
+}
+
+// shardHashTag 与集群模式相同, 只对第一个非空的{...}中的内容哈希
+func shardHashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+// hashShardKey fnv对只差末尾几个字符的输入分布不均, 再经过murmur3的fmix64打散
+func hashShardKey(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,102 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestShardedKeyRoute(t *testing.T) {
+	stores := map[string]map[string]string{}
+	var mu sync.Mutex
+	for _, connName := range []string{"shard0", "shard1", "shard2"} {
+		store := map[string]string{}
+		stores[connName] = store
+		addr := newFakeRedis(t, func(args []string) any {
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch args[0] {
+			case "SET":
+				store[args[1]] = args[2]
+				return "OK"
+			case "SCAN":
+				keys := make([]any, 0, len(store))
+				for key := range store {
+					keys = append(keys, key)
+				}
+				return []any{"0", keys}
+			}
+			return nil
+		})
+		ConnectByConf(connName, &ConnConf{Servers: []string{addr}})
+	}
+
+	const route = "sharded"
+	if err := RegisterShardedKeyRoute(route); !errors.Is(err, ErrShardConnRequired) {
+		t.Fatalf("expect ErrShardConnRequired without conns, got %v", err)
+	}
+	if err := RegisterShardedKeyRoute(route, "shard0", ""); !errors.Is(err, ErrShardConnRequired) {
+		t.Fatalf("expect ErrShardConnRequired for empty conn name, got %v", err)
+	}
+	connNames := []string{"shard0", "shard1"}
+	if err := RegisterShardedKeyRoute(route, connNames...); err != nil {
+		t.Fatal(err)
+	}
+	// 注册后修改调用方的切片不影响路由
+	connNames[1] = "shard2"
+	if match, err := ResolveKeyRoute(route); err != nil || !slices.Equal(match.Shards, []string{"shard0", "shard1"}) {
+		t.Fatalf("expect shards copied on register, got %+v %v", match, err)
+	}
+
+	if _, err := RouteConnName(route); !errors.Is(err, ErrKeyRouteSharded) {
+		t.Fatalf("expect sharded error, got %v", err)
+	}
+
+	for i := 0; i < 200; i++ {
+		if err := Set(NewKey(route, "item:%d", i), "v", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	n0, n1 := len(stores["shard0"]), len(stores["shard1"])
+	mu.Unlock()
+	if n0+n1 != 200 || n0 < 50 || n1 < 50 {
+		t.Fatalf("unbalanced shards %d %d", n0, n1)
+	}
+
+	a, _ := KeyConnName(NewKey(route, "{user:1}:profile"))
+	b, _ := KeyConnName(NewKey(route, "{user:1}:orders"))
+	if a != b {
+		t.Fatalf("expect same shard for hashtag, got %s %s", a, b)
+	}
+
+	moves, err := PlanAddKeyRouteShard(context.Background(), route, "shard2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) == 0 || len(moves) > 120 {
+		t.Fatalf("unexpected moves %d", len(moves))
+	}
+	for _, move := range moves {
+		if move.To != "shard2" {
+			t.Fatalf("expect move to new shard, got %+v", move)
+		}
+	}
+
+	if err = AddKeyRouteShard(route, "shard2"); err != nil {
+		t.Fatal(err)
+	}
+	if err = AddKeyRouteShard(route, "shard2"); !errors.Is(err, ErrShardExists) {
+		t.Fatalf("expect shard exists, got %v", err)
+	}
+
+	for _, move := range moves {
+		if connName, _ := KeyConnName(move.Key); connName != "shard2" {
+			t.Fatalf("expect %s on shard2, got %s", move.Key.Key, connName)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package routeredis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSlowLogMaxArgs    = 8
+	DefaultSlowLogMaxArgLen  = 64
+	DefaultSlowLogBufferSize = 128
+	slowLogRedacted          = "[redacted]"
+)
+
+type SlowLogConf struct {
+	Threshold       time.Duration            // 未在RouteThresholds中配置的路由使用, <=0时这些路由不记录
+	RouteThresholds map[string]time.Duration // 按Key.Route配置的阈值, <=0时该路由不记录
+	Logger          *slog.Logger             // 为空时使用slog.Default()
+	MaxArgs         int                      // 只记录前MaxArgs个参数, <=0时取DefaultSlowLogMaxArgs
+	MaxArgLen       int                      // 单个参数超过MaxArgLen字节时截断并注明原始大小, <=0时取DefaultSlowLogMaxArgLen
+	RedactRoutes    []string                 // 匹配的路由(语法同path.Match)只记录key前缀和参数大小, 用于会话令牌等敏感数据
+	BufferSize      int                      // 内存中保留的最近慢命令条数, <=0时取DefaultSlowLogBufferSize
+}
+
+// SlowCmd 一条慢命令, 管道中的一批命令记录为一条, Cmd为PIPELINE, Args为各命令的"命令名 key"
+type SlowCmd struct {
+	Time     time.Time
+	Way      string
+	Cmd      string
+	Route    string
+	ConnName string
+	Key      string
+	Args     []string
+	ArgCount int // 截取前的参数个数
+	Cost     time.Duration
+	Err      error
+}
+
+// SlowLog 通过Middleware和PipelineMiddleware接入Client, 超过阈值的命令写入slog并保存在环形缓冲中
+type SlowLog struct {
+	conf   SlowLogConf
+	logger *slog.Logger
+	mu     sync.Mutex
+	buf    []*SlowCmd
+	next   int
+	full   bool
+}
+
+func NewSlowLog(conf *SlowLogConf) *SlowLog {
+	l := &SlowLog{}
+	if conf != nil {
+		l.conf = *conf
+	}
+	if l.conf.MaxArgs <= 0 {
+		l.conf.MaxArgs = DefaultSlowLogMaxArgs
+	}
+	if l.conf.MaxArgLen <= 0 {
+		l.conf.MaxArgLen = DefaultSlowLogMaxArgLen
+	}
+	if l.conf.BufferSize <= 0 {
+		l.conf.BufferSize = DefaultSlowLogBufferSize
+	}
+	l.logger = l.conf.Logger
+	if l.logger == nil {
+		l.logger = slog.Default()
+	}
+	l.buf = make([]*SlowCmd, l.conf.BufferSize)
+	return l
+}
+
+func EnableSlowLog(conf *SlowLogConf) *SlowLog {
+	return defaultClient.EnableSlowLog(conf)
+}
+
+// EnableSlowLog 创建SlowLog并加入c的命令和管道中间件, 应在其他中间件之后注册, 避免计入重试等中间件的耗时
+func (c *Client) EnableSlowLog(conf *SlowLogConf) *SlowLog {
+	l := NewSlowLog(conf)
+	c.Use(l.Middleware())
+	c.UsePipeline(l.PipelineMiddleware())
+	return l
+}
+
+func (l *SlowLog) Middleware() CmdMiddleware {
+	return func(next CmdHandler) CmdHandler {
+		return func(ctx context.Context, cmd *Cmd) (any, error) {
+			start := time.Now()
+			res, err := next(ctx, cmd)
+			cost := time.Since(start)
+
+			if !l.isSlow(cmd.Key.Route, cost) {
+				return res, err
+			}
+
+			redact := l.isRedacted(cmd.Key.Route)
+			connName, _ := KeyConnName(cmd.Key)
+			args := cmd.Args
+			if len(args) > l.conf.MaxArgs {
+				args = args[:l.conf.MaxArgs]
+			}
+			slowCmd := &SlowCmd{
+				Time:     start,
+				Way:      cmd.Way,
+				Cmd:      cmd.Name,
+				Route:    cmd.Key.Route,
+				ConnName: connName,
+				Key:      l.formatKey(cmd.Key, redact),
+				Args:     make([]string, 0, len(args)),
+				ArgCount: len(cmd.Args),
+				Cost:     cost,
+				Err:      err,
+			}
+			for _, arg := range args {
+				slowCmd.Args = append(slowCmd.Args, l.formatArg(arg, redact))
+			}
+			l.record(ctx, slowCmd)
+
+			return res, err
+		}
+	}
+}
+
+// PipelineMiddleware 一批命令中任一路由超过阈值即记录整批
+func (l *SlowLog) PipelineMiddleware() PipelineMiddleware {
+	return func(next PipelineHandler) PipelineHandler {
+		return func(ctx context.Context, batch *PipelineBatch) {
+			start := time.Now()
+			next(ctx, batch)
+			cost := time.Since(start)
+
+			slow := false
+			for _, cmd := range batch.Cmds {
+				if l.isSlow(cmd.Key.Route, cost) {
+					slow = true
+					break
+				}
+			}
+			if !slow {
+				return
+			}
+
+			slowCmd := &SlowCmd{
+				Time:     start,
+				Way:      "Pipeline",
+				Cmd:      "PIPELINE",
+				ConnName: batch.ConnName,
+				ArgCount: len(batch.Cmds),
+				Cost:     cost,
+			}
+			for i, cmd := range batch.Cmds {
+				if slowCmd.Err == nil {
+					slowCmd.Err = cmd.Err
+				}
+				if i >= l.conf.MaxArgs {
+					continue
+				}
+				slowCmd.Args = append(slowCmd.Args, cmd.Cmd+" "+l.formatKey(cmd.Key, l.isRedacted(cmd.Key.Route)))
+			}
+			l.record(ctx, slowCmd)
+		}
+	}
+}
+
+func (l *SlowLog) isSlow(route string, cost time.Duration) bool {
+	threshold, ok := l.conf.RouteThresholds[route]
+	if !ok {
+		threshold = l.conf.Threshold
+	}
+	return threshold > 0 && cost >= threshold
+}
+
+func (l *SlowLog) isRedacted(route string) bool {
+	for _, pattern := range l.conf.RedactRoutes {
+		if ok, _ := path.Match(pattern, route); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *SlowLog) formatKey(key *Key, redact bool) string {
+	redisKey := key.RedisKey()
+	if !redact {
+		return redisKey
+	}
+	return redisKey[:len(redisKey)-len(key.Key)] + slowLogRedacted
+}
+
+func (l *SlowLog) formatArg(arg any, redact bool) string {
+	var s string
+	switch v := arg.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if redact {
+		return slowLogRedacted + "(" + strconv.Itoa(len(s)) + " bytes)"
+	}
+	if len(s) > l.conf.MaxArgLen {
+		return s[:l.conf.MaxArgLen] + "...(" + strconv.Itoa(len(s)) + " bytes)"
+	}
+	return s
+}
+
+func (l *SlowLog) record(ctx context.Context, cmd *SlowCmd) {
+	l.mu.Lock()
+	l.buf[l.next] = cmd
+	l.next = (l.next + 1) % len(l.buf)
+	if l.next == 0 {
+		l.full = true
+	}
+	l.mu.Unlock()
+
+	attrs := []slog.Attr{
+		slog.String("way", cmd.Way),
+		slog.String("cmd", cmd.Cmd),
+		slog.String("route", cmd.Route),
+		slog.String("conn", cmd.ConnName),
+		slog.String("key", cmd.Key),
+		slog.Any("args", cmd.Args),
+		slog.Int("arg_count", cmd.ArgCount),
+		slog.Duration("cost", cmd.Cost),
+	}
+	if cmd.Err != nil {
+		attrs = append(attrs, slog.String("err", cmd.Err.Error()))
+	}
+	l.logger.LogAttrs(ctx, slog.LevelWarn, "redis slow command", attrs...)
+}
+
+// Recent 最近的n条慢命令, 最新的在前, n<=0时返回缓冲中的全部
+func (l *SlowLog) Recent(n int) []*SlowCmd {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	size := l.next
+	if l.full {
+		size = len(l.buf)
+	}
+	if n <= 0 || n > size {
+		n = size
+	}
+
+	cmds := make([]*SlowCmd, 0, n)
+	for i := 1; i <= n; i++ {
+		cmds = append(cmds, l.buf[(l.next-i+len(l.buf))%len(l.buf)])
+	}
+	return cmds
+}
+
+// Reset 清空缓冲
+func (l *SlowLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clear(l.buf)
+	l.next, l.full = 0, false
+}
@@ -0,0 +1,75 @@
+package routeredis
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowLog(t *testing.T) {
+	addr := newFakeRedis(t, func(args []string) any {
+		return "OK"
+	})
+
+	c := NewClient()
+	if err := c.ConnectByConf("main", &ConnConf{Servers: []string{addr}}); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRouteWithOpts("slow.user", "main", &KeyRouteOpts{Prefix: "user:"})
+	c.RegisterKeyRouteWithOpts("slow.session", "main", &KeyRouteOpts{Prefix: "sess:"})
+	c.RegisterKeyRoute("slow.fast", "main")
+
+	var out bytes.Buffer
+	l := c.EnableSlowLog(&SlowLogConf{
+		Threshold:       time.Nanosecond,
+		RouteThresholds: map[string]time.Duration{"slow.fast": time.Hour},
+		Logger:          slog.New(slog.NewTextHandler(&out, nil)),
+		MaxArgs:         2,
+		MaxArgLen:       4,
+		RedactRoutes:    []string{"slow.sess*"},
+		BufferSize:      2,
+	})
+
+	if err := Set(c.NewKey("slow.fast", "1"), "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DoCmdWithTTL(nil, "HSET", c.NewKey("slow.user", "1"), "field", "0123456789", "extra"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(c.NewKey("slow.session", "token"), "secret", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := l.Recent(0)
+	if len(cmds) != 2 {
+		t.Fatalf("expect 2 slow cmds, got %d", len(cmds))
+	}
+
+	session, user := cmds[0], cmds[1]
+	if session.Key != "sess:[redacted]" || session.Args[0] != "[redacted](6 bytes)" {
+		t.Fatalf("expect redacted session, got %+v", session)
+	}
+	if user.Key != "user:1" || user.ArgCount != 3 || len(user.Args) != 2 || user.Args[1] != "0123...(10 bytes)" {
+		t.Fatalf("expect sampled and truncated args, got %+v", user)
+	}
+	if strings.Contains(out.String(), "secret") || !strings.Contains(out.String(), "redis slow command") {
+		t.Fatalf("unexpected log output %s", out.String())
+	}
+
+	// 缓冲满后覆盖最旧的一条
+	p := NewPipeline()
+	p.Do(nil, "GET", c.NewKey("slow.user", "2"))
+	if _, err := p.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if cmds = l.Recent(0); len(cmds) != 2 || cmds[0].Cmd != "PIPELINE" || cmds[1] != session {
+		t.Fatalf("unexpected ring buffer %+v", cmds)
+	}
+
+	l.Reset()
+	if cmds = l.Recent(0); len(cmds) != 0 {
+		t.Fatalf("expect empty after reset, got %d", len(cmds))
+	}
+}
@@ -1,14 +1,18 @@
 package routeredis
 
 import (
+	"context"
 	"errors"
 
 	"github.com/gomodule/redigo/redis"
-	jsoniter "github.com/json-iterator/go"
 )
 
 func Get(key *Key, data any) (string, bool, error) {
-	res, err := redis.String(DoCmdWithTTL(nil, "GET", key))
+	return GetCtx(context.Background(), key, data)
+}
+
+func GetCtx(ctx context.Context, key *Key, data any) (string, bool, error) {
+	res, err := redis.String(DoCmdWithTTLContext(ctx, nil, "GET", key))
 	if err != nil {
 		if errors.Is(err, redis.ErrNil) {
 			return "", false, nil
@@ -19,55 +23,70 @@ func Get(key *Key, data any) (string, bool, error) {
 }
 
 func GetObj(key *Key, data any) (bool, error) {
-	res, err := redis.String(DoCmdWithTTL(nil, "GET", key))
+	return GetObjCtx(context.Background(), key, data)
+}
+
+func GetObjCtx(ctx context.Context, key *Key, data any) (bool, error) {
+	res, err := redis.Bytes(DoCmdWithTTLContext(ctx, nil, "GET", key))
 	if err != nil {
 		if errors.Is(err, redis.ErrNil) {
 			return false, nil
 		}
 		return false, err
 	}
-	err = jsoniter.UnmarshalFromString(res, data)
+	err = decodeValue(key, res, data)
 	if err != nil {
 		return false, err
 	}
 	return true, err
 }
 
+// Deprecated: 使用GetAs[int64]
 func GetInt64(key *Key) (int64, bool, error) {
-	res, err := redis.Int64(DoCmdWithTTL(nil, "GET", key))
-	if err != nil {
-		if errors.Is(err, redis.ErrNil) {
-			return 0, false, nil
-		}
-		return 0, false, err
-	}
-	return res, true, nil
+	return GetInt64Ctx(context.Background(), key)
+}
+
+// Deprecated: 使用GetAsCtx[int64]
+func GetInt64Ctx(ctx context.Context, key *Key) (int64, bool, error) {
+	return GetAsCtx[int64](ctx, key)
 }
 
 func GetFloat64(key *Key) (float64, error) {
-	return redis.Float64(DoCmdWithTTL(nil, "GET", key))
+	return GetFloat64Ctx(context.Background(), key)
+}
+
+func GetFloat64Ctx(ctx context.Context, key *Key) (float64, error) {
+	return redis.Float64(DoCmdWithTTLContext(ctx, nil, "GET", key))
 }
 
 func Del(key *Key) error {
-	_, err := DoCmdWithTTL(nil, "DEL", key)
+	return DelCtx(context.Background(), key)
+}
+
+func DelCtx(ctx context.Context, key *Key) error {
+	_, err := DoCmdWithTTLContext(ctx, nil, "DEL", key)
 	return err
 }
 
 func AsyncDel(key *Key) error {
-	return SendCmdWithTTL(nil, "DEL", key)
+	return AsyncDelCtx(context.Background(), key)
+}
+
+func AsyncDelCtx(ctx context.Context, key *Key) error {
+	return SendCmdWithTTLContext(ctx, nil, "DEL", key)
 }
 
 func Setex(key *Key, ttl int64, data any) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return SetexCtx(context.Background(), key, ttl, data)
+}
+
+func SetexCtx(ctx context.Context, key *Key, ttl int64, data any) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	_, err := DoCmdWithTTL(nil, "SETEX", key, ttl, str)
+	_, err = DoCmdWithTTLContext(ctx, nil, "SETEX", key, ttl, str)
 	if err != nil {
 		return err
 	}
@@ -76,32 +95,32 @@ func Setex(key *Key, ttl int64, data any) error {
 }
 
 func AsyncSetex(key *Key, ttl int64, data any) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return AsyncSetexCtx(context.Background(), key, ttl, data)
+}
+
+func AsyncSetexCtx(ctx context.Context, key *Key, ttl int64, data any) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
-	return SendCmdWithTTL(nil, "SETEX", key, ttl, str)
+	return SendCmdWithTTLContext(ctx, nil, "SETEX", key, ttl, str)
 }
 
 func Set(key *Key, data any, ttl int64) error {
+	return SetCtx(context.Background(), key, data, ttl)
+}
+
+func SetCtx(ctx context.Context, key *Key, data any, ttl int64) error {
 	if ttl > 0 {
-		return Setex(key, ttl, data)
+		return SetexCtx(ctx, key, ttl, data)
 	}
 
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	if _, err := DoCmdWithTTL(nil, "SET", key, str); err != nil {
+	if _, err := DoCmdWithTTLContext(ctx, nil, "SET", key, str); err != nil {
 		return err
 	}
 
@@ -109,20 +128,20 @@ func Set(key *Key, data any, ttl int64) error {
 }
 
 func AsyncSet(key *Key, data any, ttl int64) error {
+	return AsyncSetCtx(context.Background(), key, data, ttl)
+}
+
+func AsyncSetCtx(ctx context.Context, key *Key, data any, ttl int64) error {
 	if ttl > 0 {
-		return AsyncSetex(key, ttl, data)
+		return AsyncSetexCtx(ctx, key, ttl, data)
 	}
 
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	if err := SendCmdWithTTL(nil, "SET", key, str); err != nil {
+	if err := SendCmdWithTTLContext(ctx, nil, "SET", key, str); err != nil {
 		return err
 	}
 
@@ -130,7 +149,11 @@ func AsyncSet(key *Key, data any, ttl int64) error {
 }
 
 func Incrby(key *Key, inc int64, ttl int64) (int64, error) {
-	res, err := redis.Int64(DoCmdWithTTL(NewAsyncSecTTL(ttl), "INCRBY", key, inc))
+	return IncrbyCtx(context.Background(), key, inc, ttl)
+}
+
+func IncrbyCtx(ctx context.Context, key *Key, inc int64, ttl int64) (int64, error) {
+	res, err := redis.Int64(DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "INCRBY", key, inc))
 	if err != nil {
 		return 0, err
 	}
@@ -139,7 +162,11 @@ func Incrby(key *Key, inc int64, ttl int64) (int64, error) {
 }
 
 func AsyncIncrby(key *Key, inc int64, ttl int64) error {
-	if err := SendCmdWithTTL(NewAsyncSecTTL(ttl), "INCRBY", key, inc); err != nil {
+	return AsyncIncrbyCtx(context.Background(), key, inc, ttl)
+}
+
+func AsyncIncrbyCtx(ctx context.Context, key *Key, inc int64, ttl int64) error {
+	if err := SendCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "INCRBY", key, inc); err != nil {
 		return err
 	}
 
@@ -147,7 +174,25 @@ func AsyncIncrby(key *Key, inc int64, ttl int64) error {
 }
 
 func Setnx(key *Key, data any, ttl int64) (bool, error) {
-	res, err := redis.Int(DoCmdWithTTL(NewAsyncSecTTL(ttl), "SETNX", key, data))
+	return SetnxCtx(context.Background(), key, data, ttl)
+}
+
+// SetnxCtx ttl>0时使用SET NX EX原子地设置过期时间, 避免SETNX成功而EXPIRE失败留下永不过期的key
+func SetnxCtx(ctx context.Context, key *Key, data any, ttl int64) (bool, error) {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return false, err
+	}
+
+	if ttl > 0 {
+		res, err := DoCmdWithTTLContext(ctx, nil, "SET", key, str, "NX", "EX", ttl)
+		if err != nil {
+			return false, err
+		}
+		return res != nil, nil
+	}
+
+	res, err := redis.Int(DoCmdWithTTLContext(ctx, nil, "SETNX", key, str))
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,184 @@
+package routeredis
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+type StreamEntry struct {
+	Id     string
+	Fields map[string]string
+}
+
+// XAdd maxLen>0时按MAXLEN裁剪, approx为true时使用近似裁剪(MAXLEN ~), 返回新消息的id
+func XAdd(key *Key, maxLen int64, approx bool, ttl int64, fields map[string]any) (string, error) {
+	return XAddCtx(context.Background(), key, maxLen, approx, ttl, fields)
+}
+
+func XAddCtx(ctx context.Context, key *Key, maxLen int64, approx bool, ttl int64, fields map[string]any) (string, error) {
+	args, err := xAddArgs(key, maxLen, approx, fields)
+	if err != nil {
+		return "", err
+	}
+
+	return redis.String(DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "XADD", key, args...))
+}
+
+func AsyncXAdd(key *Key, maxLen int64, approx bool, ttl int64, fields map[string]any) error {
+	return AsyncXAddCtx(context.Background(), key, maxLen, approx, ttl, fields)
+}
+
+func AsyncXAddCtx(ctx context.Context, key *Key, maxLen int64, approx bool, ttl int64, fields map[string]any) error {
+	args, err := xAddArgs(key, maxLen, approx, fields)
+	if err != nil {
+		return err
+	}
+
+	return SendCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "XADD", key, args...)
+}
+
+func xAddArgs(key *Key, maxLen int64, approx bool, fields map[string]any) ([]any, error) {
+	args := make([]any, 0, 4+len(fields)*2)
+	if maxLen > 0 {
+		args = append(args, "MAXLEN")
+		if approx {
+			args = append(args, "~")
+		}
+		args = append(args, maxLen)
+	}
+	args = append(args, "*")
+
+	for field, data := range fields {
+		str, err := encodeValue(key, data)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, field, str)
+	}
+
+	return args, nil
+}
+
+// XRange count<=0时不限制返回数量, start和end可以使用"-"和"+"
+func XRange(key *Key, start, end string, count int64) ([]*StreamEntry, error) {
+	return XRangeCtx(context.Background(), key, start, end, count)
+}
+
+func XRangeCtx(ctx context.Context, key *Key, start, end string, count int64) ([]*StreamEntry, error) {
+	args := []any{start, end}
+	if count > 0 {
+		args = append(args, "COUNT", count)
+	}
+	return parseStreamEntries(DoCmdWithTTLContext(ctx, nil, "XRANGE", key, args...))
+}
+
+func XLen(key *Key) (int64, error) {
+	return XLenCtx(context.Background(), key)
+}
+
+func XLenCtx(ctx context.Context, key *Key) (int64, error) {
+	return redis.Int64(DoCmdWithTTLContext(ctx, nil, "XLEN", key))
+}
+
+func XDel(key *Key, ids ...string) (int64, error) {
+	return XDelCtx(context.Background(), key, ids...)
+}
+
+func XDelCtx(ctx context.Context, key *Key, ids ...string) (int64, error) {
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return redis.Int64(DoCmdWithTTLContext(ctx, nil, "XDEL", key, args...))
+}
+
+func XAck(key *Key, group string, ids ...string) (int64, error) {
+	return XAckCtx(context.Background(), key, group, ids...)
+}
+
+func XAckCtx(ctx context.Context, key *Key, group string, ids ...string) (int64, error) {
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, group)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return redis.Int64(DoCmdWithTTLContext(ctx, nil, "XACK", key, args...))
+}
+
+func parseStreamEntries(reply any, err error) ([]*StreamEntry, error) {
+	items, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*StreamEntry, 0, len(items))
+	for _, item := range items {
+		entry, err := redis.Values(item, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(entry) != 2 {
+			continue
+		}
+
+		id, err := redis.String(entry[0], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// 已被XDEL删除但仍在pending列表中的消息字段为空
+		var fields map[string]string
+		if entry[1] != nil {
+			if fields, err = redis.StringMap(entry[1], nil); err != nil {
+				return nil, err
+			}
+		}
+
+		entries = append(entries, &StreamEntry{
+			Id:     id,
+			Fields: fields,
+		})
+	}
+
+	return entries, nil
+}
+
+// doStreamCmd 用于key不在首个参数位置的命令(XGROUP, XREADGROUP), 集群模式下RetryConn无法据此定位节点, 需要按key绑定节点
+func doStreamCmd(ctx context.Context, key *Key, cmd string, args ...any) (res any, err error) {
+	if onCmdDone := key.Client().cmdDoneHook(); onCmdDone != nil {
+		start := time.Now()
+		defer func() {
+			onCmdDone("DoStreamCmd", nil, time.Since(start), cmd, key, err, args...)
+		}()
+	}
+
+	pool, err := KeyConnPool(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn redis.Conn
+	if cluster, ok := pool.(*RedisCluster); ok {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		conn = cluster.Cluster.Get()
+		if err = redisc.BindConn(conn, key.RedisKey()); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	} else if conn, err = getPoolConnContext(ctx, pool); err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err = conn.Err(); err != nil {
+		return nil, err
+	}
+
+	return doContext(ctx, conn, cmd, args...)
+}
@@ -0,0 +1,279 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	DefaultStreamConsumerCount         = 10
+	DefaultStreamConsumerBlock         = 2 * time.Second
+	DefaultStreamConsumerClaimMinIdle  = time.Minute
+	DefaultStreamConsumerClaimInterval = 30 * time.Second
+	streamConsumerMinBackoff           = 100 * time.Millisecond
+	streamConsumerMaxBackoff           = 5 * time.Second
+)
+
+// StreamHandler 返回nil时消息被XACK, 返回错误时消息留在pending列表中, 空闲超过claimMinIdle后被重新认领
+type StreamHandler func(ctx context.Context, entry *StreamEntry) error
+
+// StreamConsumer 消费组的工作者, 启动时自动创建消费组(MKSTREAM), 先处理自身未确认的消息再读取新消息,
+// 并定期通过XAUTOCLAIM认领组内其他消费者遗留的超时消息
+type StreamConsumer struct {
+	key           *Key
+	group         string
+	consumer      string
+	handler       StreamHandler
+	count         int64
+	block         time.Duration
+	claimMinIdle  time.Duration
+	claimInterval time.Duration
+	startId       string
+	onErr         func(err error)
+}
+
+func NewStreamConsumer(key *Key, group, consumer string, handler StreamHandler) *StreamConsumer {
+	return &StreamConsumer{
+		key:           key,
+		group:         group,
+		consumer:      consumer,
+		handler:       handler,
+		count:         DefaultStreamConsumerCount,
+		block:         DefaultStreamConsumerBlock,
+		claimMinIdle:  DefaultStreamConsumerClaimMinIdle,
+		claimInterval: DefaultStreamConsumerClaimInterval,
+		startId:       "$",
+	}
+}
+
+// SetCount 每次XREADGROUP和XAUTOCLAIM最多读取的消息数
+func (c *StreamConsumer) SetCount(count int64) *StreamConsumer {
+	c.count = count
+	return c
+}
+
+// SetBlock XREADGROUP的阻塞时间, 需要小于连接的读超时
+func (c *StreamConsumer) SetBlock(block time.Duration) *StreamConsumer {
+	c.block = block
+	return c
+}
+
+// SetClaim minIdle为消息被认领前的最小空闲时间, interval<=0时不认领
+func (c *StreamConsumer) SetClaim(minIdle, interval time.Duration) *StreamConsumer {
+	c.claimMinIdle, c.claimInterval = minIdle, interval
+	return c
+}
+
+// SetStartId 消费组不存在时创建消费组的起始id, 默认"$"只消费创建之后的消息, "0"从头消费
+func (c *StreamConsumer) SetStartId(id string) *StreamConsumer {
+	c.startId = id
+	return c
+}
+
+// SetOnError 读取, 确认和认领出错时回调, 出错后Run退避重试而不会退出
+func (c *StreamConsumer) SetOnError(onErr func(err error)) *StreamConsumer {
+	c.onErr = onErr
+	return c
+}
+
+// Run 阻塞消费直到ctx结束, 返回ctx.Err()
+func (c *StreamConsumer) Run(ctx context.Context) error {
+	var (
+		groupReady bool
+		pendingId  = "0"
+		lastClaim  time.Time
+		backoff    = streamConsumerMinBackoff
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := func() error {
+			if !groupReady {
+				if err := c.CreateGroup(ctx); err != nil {
+					return err
+				}
+				groupReady = true
+			}
+
+			if c.claimInterval > 0 && time.Since(lastClaim) >= c.claimInterval {
+				if err := c.claim(ctx); err != nil {
+					return err
+				}
+				lastClaim = time.Now()
+			}
+
+			// 重启后先从"0"开始逐批读取自身pending列表中未确认的消息, 读完再读取新消息
+			id := ">"
+			if pendingId != "" {
+				id = pendingId
+			}
+			entries, err := c.read(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			if pendingId != "" {
+				pendingId = ""
+				if len(entries) > 0 {
+					pendingId = entries[len(entries)-1].Id
+				}
+			}
+
+			return c.handle(ctx, entries)
+		}()
+		if err == nil {
+			backoff = streamConsumerMinBackoff
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if isNoGroupErr(err) {
+			groupReady, pendingId = false, "0"
+		}
+
+		if c.onErr != nil {
+			c.onErr(err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > streamConsumerMaxBackoff {
+			backoff = streamConsumerMaxBackoff
+		}
+	}
+}
+
+// CreateGroup 创建消费组, stream不存在时一并创建, 消费组已存在时不报错
+func (c *StreamConsumer) CreateGroup(ctx context.Context) error {
+	_, err := doStreamCmd(ctx, c.key, "XGROUP", "CREATE", c.key.RedisKey(), c.group, c.startId, "MKSTREAM")
+	if isBusyGroupErr(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *StreamConsumer) read(ctx context.Context, id string) ([]*StreamEntry, error) {
+	args := []any{"GROUP", c.group, c.consumer}
+	if c.count > 0 {
+		args = append(args, "COUNT", c.count)
+	}
+	// 读取pending列表时不阻塞
+	if id == ">" && c.block > 0 {
+		args = append(args, "BLOCK", c.block.Milliseconds())
+	}
+	args = append(args, "STREAMS", c.key.RedisKey(), id)
+
+	streams, err := redis.Values(doStreamCmd(ctx, c.key, "XREADGROUP", args...))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []*StreamEntry
+	for _, stream := range streams {
+		pair, err := redis.Values(stream, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(pair) != 2 {
+			continue
+		}
+		items, err := parseStreamEntries(pair[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, items...)
+	}
+
+	return entries, nil
+}
+
+// claim 从头遍历消费组的pending列表, 将空闲超过claimMinIdle的消息认领到当前消费者并处理
+func (c *StreamConsumer) claim(ctx context.Context) error {
+	start := "0-0"
+	for {
+		args := []any{c.key.RedisKey(), c.group, c.consumer, c.claimMinIdle.Milliseconds(), start}
+		if c.count > 0 {
+			args = append(args, "COUNT", c.count)
+		}
+
+		res, err := redis.Values(doStreamCmd(ctx, c.key, "XAUTOCLAIM", args...))
+		if err != nil {
+			return err
+		}
+		if len(res) < 2 {
+			return nil
+		}
+
+		if start, err = redis.String(res[0], nil); err != nil {
+			return err
+		}
+
+		entries, err := parseStreamEntries(res[1], nil)
+		if err != nil {
+			return err
+		}
+
+		if err = c.handle(ctx, entries); err != nil {
+			return err
+		}
+
+		if start == "0-0" {
+			return nil
+		}
+	}
+}
+
+func (c *StreamConsumer) handle(ctx context.Context, entries []*StreamEntry) error {
+	var acks []string
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// 消息已被XDEL删除, 直接确认将其移出pending列表
+		if entry.Fields == nil {
+			acks = append(acks, entry.Id)
+			continue
+		}
+
+		if c.handler(ctx, entry) == nil {
+			acks = append(acks, entry.Id)
+		}
+	}
+
+	if len(acks) == 0 {
+		return nil
+	}
+
+	// 处理期间ctx可能已经结束, 确认不应因此丢失
+	_, err := XAckCtx(context.WithoutCancel(ctx), c.key, c.group, acks...)
+	return err
+}
+
+func isNoGroupErr(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "NOGROUP")
+}
+
+func isBusyGroupErr(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "BUSYGROUP")
+}
@@ -0,0 +1,103 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestStreamConsumer(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		acked   = map[string]bool{}
+		newRead bool
+	)
+	entry := func(id string) any {
+		return []any{id, []any{"n", id}}
+	}
+	addr := newFakeRedis(t, func(args []string) any {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch args[0] {
+		case "XGROUP":
+			return redis.Error("BUSYGROUP Consumer Group name already exists")
+		case "XREADGROUP":
+			var entries []any
+			switch args[len(args)-1] {
+			case "0":
+				entries = []any{entry("1-0")}
+			case ">":
+				if newRead {
+					return nil
+				}
+				newRead = true
+				entries = []any{entry("2-0"), entry("4-0")}
+			default:
+				return []any{[]any{"stream", []any{}}}
+			}
+			return []any{[]any{"stream", entries}}
+		case "XAUTOCLAIM":
+			return []any{"0-0", []any{entry("3-0"), []any{"5-0", nil}}}
+		case "XACK":
+			for _, id := range args[3:] {
+				acked[id] = true
+			}
+			return len(args) - 3
+		}
+		return redis.Error("ERR unknown command")
+	})
+
+	const route = "stream"
+	ConnectByConf(route, &ConnConf{Servers: []string{addr}})
+	RegisterKeyRoute(route, route)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	consumer := NewStreamConsumer(NewKey(route, "stream"), "group", "c1", func(ctx context.Context, entry *StreamEntry) error {
+		if entry.Id == "4-0" {
+			return errors.New("fail")
+		}
+		return nil
+	}).SetBlock(10 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- consumer.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(acked)
+		mu.Unlock()
+		if n >= 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expect 4 acks, got %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect canceled, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range []string{"1-0", "2-0", "3-0", "5-0"} {
+		if !acked[id] {
+			t.Fatalf("expect %s acked", id)
+		}
+	}
+	if acked["4-0"] {
+		t.Fatal("failed entry should stay pending")
+	}
+}
@@ -0,0 +1,465 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var ErrSubscriberClosed = errors.New("redis subscriber closed")
+
+const (
+	DefaultSubscriberBufferSize = 128
+	subscriberPingInterval      = 30 * time.Second
+	subscriberMinBackoff        = 100 * time.Millisecond
+	subscriberMaxBackoff        = 5 * time.Second
+)
+
+type Message struct {
+	Route   string
+	Channel string
+	Pattern string // 通过PSubscribe收到的消息才有
+	Data    []byte
+	client  *Client
+}
+
+// Decode 与Publish的编码对应, 字符串原样发布, 其余类型按路由的Codec解码
+func (m *Message) Decode(v any) error {
+	if str, ok := v.(*string); ok {
+		*str = string(m.Data)
+		return nil
+	}
+	client := m.client
+	if client == nil {
+		client = defaultClient
+	}
+	return client.RouteCodec(m.Route).Unmarshal(m.Data, v)
+}
+
+type MessageHandler func(msg *Message)
+
+// Subscriber Publish的订阅端, 频道和模式通过Key指定并按key所属Client的路由解析连接, 每个连接使用一条订阅连接,
+// 连接断开或者连接池被Connect替换后自动重连并重新订阅
+type Subscriber struct {
+	handler MessageHandler
+	msgCh   chan *Message
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	conns   map[subConnKey]*subConn
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+// NewSubscriber 消息通过Messages返回的通道投递
+func NewSubscriber() *Subscriber {
+	return newSubscriber(nil, make(chan *Message, DefaultSubscriberBufferSize))
+}
+
+// NewSubscriberWithHandler 消息在订阅连接的接收协程中回调handler, handler不应长时间阻塞
+func NewSubscriberWithHandler(handler MessageHandler) *Subscriber {
+	return newSubscriber(handler, nil)
+}
+
+func newSubscriber(handler MessageHandler, msgCh chan *Message) *Subscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Subscriber{
+		handler: handler,
+		msgCh:   msgCh,
+		ctx:     ctx,
+		cancel:  cancel,
+		conns:   map[subConnKey]*subConn{},
+	}
+}
+
+// Messages 使用NewSubscriberWithHandler创建时返回nil, Close之后通道被关闭
+func (s *Subscriber) Messages() <-chan *Message {
+	return s.msgCh
+}
+
+func (s *Subscriber) Subscribe(channels ...*Key) error {
+	return s.update(channels, func(c *subConn, names []string, routes []string) error {
+		return c.subscribe(false, names, routes)
+	})
+}
+
+func (s *Subscriber) PSubscribe(patterns ...*Key) error {
+	return s.update(patterns, func(c *subConn, names []string, routes []string) error {
+		return c.subscribe(true, names, routes)
+	})
+}
+
+func (s *Subscriber) Unsubscribe(channels ...*Key) error {
+	return s.update(channels, func(c *subConn, names []string, _ []string) error {
+		return c.unsubscribe(false, names)
+	})
+}
+
+func (s *Subscriber) PUnsubscribe(patterns ...*Key) error {
+	return s.update(patterns, func(c *subConn, names []string, _ []string) error {
+		return c.unsubscribe(true, names)
+	})
+}
+
+func (s *Subscriber) update(keys []*Key, fn func(c *subConn, names []string, routes []string) error) error {
+	type group struct {
+		names  []string
+		routes []string
+	}
+	groups := map[subConnKey]*group{}
+	for _, key := range keys {
+		connName, err := KeyConnName(key)
+		if err != nil {
+			return err
+		}
+		connKey := subConnKey{client: key.Client(), connName: connName}
+		g, ok := groups[connKey]
+		if !ok {
+			g = &group{}
+			groups[connKey] = g
+		}
+		g.names = append(g.names, key.RedisKey())
+		g.routes = append(g.routes, key.Route)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSubscriberClosed
+	}
+
+	for connKey, g := range groups {
+		c, ok := s.conns[connKey]
+		if !ok {
+			c = newSubConn(s, connKey.client, connKey.connName)
+			s.conns[connKey] = c
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				c.run()
+			}()
+		}
+		if err := fn(c, g.names, g.routes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close 退订全部频道并等待接收协程退出, 之后关闭Messages通道
+func (s *Subscriber) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for _, c := range s.conns {
+		c.removeListener()
+	}
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
+
+	if s.msgCh != nil {
+		close(s.msgCh)
+	}
+
+	return nil
+}
+
+func (s *Subscriber) deliver(msg *Message) {
+	if s.handler != nil {
+		s.handler(msg)
+		return
+	}
+
+	select {
+	case s.msgCh <- msg:
+	case <-s.ctx.Done():
+	}
+}
+
+type subConnKey struct {
+	client   *Client
+	connName string
+}
+
+type subConn struct {
+	sub            *Subscriber
+	client         *Client
+	connName       string
+	swapCh         chan struct{}
+	removeListener func()
+	mu             sync.Mutex
+	psc            *redis.PubSubConn
+	channels       map[string]string // channel -> route
+	patterns       map[string]string
+}
+
+func newSubConn(sub *Subscriber, client *Client, connName string) *subConn {
+	c := &subConn{
+		sub:      sub,
+		client:   client,
+		connName: connName,
+		swapCh:   make(chan struct{}, 1),
+		channels: map[string]string{},
+		patterns: map[string]string{},
+	}
+	c.removeListener = client.addConnSwapListener(func(swapped string) {
+		if swapped != connName {
+			return
+		}
+		select {
+		case c.swapCh <- struct{}{}:
+		default:
+		}
+	})
+	return c
+}
+
+func (c *subConn) subscribe(isPattern bool, names []string, routes []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	subs := c.channels
+	if isPattern {
+		subs = c.patterns
+	}
+	args := make([]any, 0, len(names))
+	for i, name := range names {
+		subs[name] = routes[i]
+		args = append(args, name)
+	}
+
+	// 尚未连上时由run在建立连接后统一订阅
+	if c.psc == nil {
+		return nil
+	}
+
+	if isPattern {
+		return c.psc.PSubscribe(args...)
+	}
+	return c.psc.Subscribe(args...)
+}
+
+func (c *subConn) unsubscribe(isPattern bool, names []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	subs := c.channels
+	if isPattern {
+		subs = c.patterns
+	}
+	args := make([]any, 0, len(names))
+	for _, name := range names {
+		delete(subs, name)
+		args = append(args, name)
+	}
+
+	if c.psc == nil {
+		return nil
+	}
+
+	if isPattern {
+		return c.psc.PUnsubscribe(args...)
+	}
+	return c.psc.Unsubscribe(args...)
+}
+
+func (c *subConn) run() {
+	backoff := subscriberMinBackoff
+	for {
+		start := time.Now()
+		swapped := c.serve()
+
+		if c.sub.ctx.Err() != nil {
+			return
+		}
+
+		if swapped || time.Since(start) > subscriberMaxBackoff {
+			backoff = subscriberMinBackoff
+			continue
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-c.sub.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > subscriberMaxBackoff {
+			backoff = subscriberMaxBackoff
+		}
+	}
+}
+
+// serve 建立订阅连接并接收消息直到连接出错, 连接池被替换或者Subscriber关闭, 连接池被替换时返回true
+func (c *subConn) serve() bool {
+	conn, err := c.dial()
+	if err != nil {
+		return false
+	}
+
+	psc := &redis.PubSubConn{Conn: conn}
+	if err = c.attach(psc); err != nil {
+		c.detach()
+		_ = psc.Close()
+		return false
+	}
+
+	stopping := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.receive(psc, stopping)
+	}()
+
+	// 先摘除连接避免继续写入, 关闭连接后接收协程必然退出, 等待它结束再返回
+	defer func() {
+		c.detach()
+		_ = psc.Close()
+		<-done
+	}()
+
+	ticker := time.NewTicker(subscriberPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return false
+		case <-ticker.C:
+			c.mu.Lock()
+			err = psc.Ping("")
+			c.mu.Unlock()
+			if err != nil {
+				return false
+			}
+		case <-c.swapCh:
+			c.stop(psc, stopping, done)
+			return true
+		case <-c.sub.ctx.Done():
+			c.stop(psc, stopping, done)
+			return false
+		}
+	}
+}
+
+func (c *subConn) dial() (redis.Conn, error) {
+	pool, err := c.client.GetConnPool(c.connName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 订阅需要Receive, 集群模式下不能使用RetryConn, 任意节点都能收到集群内广播的消息,
+	// 其他连接池按Subscriber的ctx等待连接, 连接池耗尽时Close可以取消等待
+	var conn redis.Conn
+	if cluster, ok := pool.(*RedisCluster); ok {
+		conn = cluster.Cluster.Get()
+	} else if conn, err = getPoolConnContext(c.sub.ctx, pool); err != nil {
+		return nil, err
+	}
+
+	if err = conn.Err(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (c *subConn) attach(psc *redis.PubSubConn) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.psc = psc
+
+	if len(c.channels) > 0 {
+		args := make([]any, 0, len(c.channels))
+		for channel := range c.channels {
+			args = append(args, channel)
+		}
+		if err := psc.Subscribe(args...); err != nil {
+			return err
+		}
+	}
+
+	if len(c.patterns) > 0 {
+		args := make([]any, 0, len(c.patterns))
+		for pattern := range c.patterns {
+			args = append(args, pattern)
+		}
+		if err := psc.PSubscribe(args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *subConn) detach() {
+	c.mu.Lock()
+	c.psc = nil
+	c.mu.Unlock()
+}
+
+// stop 退订全部频道, 接收协程收到订阅数归零的应答后退出
+func (c *subConn) stop(psc *redis.PubSubConn, stopping chan struct{}, done chan struct{}) {
+	close(stopping)
+
+	c.mu.Lock()
+	err := psc.Unsubscribe()
+	if err == nil {
+		err = psc.PUnsubscribe()
+	}
+	c.mu.Unlock()
+
+	if err == nil {
+		<-done
+	}
+}
+
+func (c *subConn) receive(psc *redis.PubSubConn, stopping chan struct{}) {
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			c.mu.Lock()
+			route := c.channels[v.Channel]
+			if v.Pattern != "" {
+				route = c.patterns[v.Pattern]
+			}
+			c.mu.Unlock()
+
+			// 订阅时频道和模式都加上了路由前缀, 投递时去掉
+			prefix := c.client.connKeyPrefix(route, c.connName)
+			c.sub.deliver(&Message{
+				Route:   route,
+				Channel: strings.TrimPrefix(v.Channel, prefix),
+				Pattern: strings.TrimPrefix(v.Pattern, prefix),
+				Data:    v.Data,
+				client:  c.client,
+			})
+		case redis.Subscription:
+			if v.Count > 0 {
+				continue
+			}
+			select {
+			case <-stopping:
+				return
+			default:
+			}
+		case error:
+			return
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package routeredis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscriberResubscribeOnConnSwap(t *testing.T) {
+	newServer := func(payload string) string {
+		return newFakeRedis(t, func(args []string) any {
+			switch args[0] {
+			case "SUBSCRIBE":
+				return fakeRedisReplies{
+					[]any{"subscribe", args[1], 1},
+					[]any{"message", args[1], payload},
+				}
+			case "UNSUBSCRIBE":
+				return []any{"unsubscribe", nil, 0}
+			case "PUNSUBSCRIBE":
+				return []any{"punsubscribe", nil, 0}
+			case "ECHO":
+				return args[1]
+			}
+			return "PONG"
+		})
+	}
+
+	const route = "sub_swap"
+	ConnectByConf(route, &ConnConf{Servers: []string{newServer(`{"id":1}`)}})
+	RegisterKeyRoute(route, route)
+
+	sub := NewSubscriber()
+	if err := sub.Subscribe(NewKey(route, "events")); err != nil {
+		t.Fatal(err)
+	}
+
+	type event struct {
+		Id int `json:"id"`
+	}
+	expectEvent := func(id int) {
+		t.Helper()
+		select {
+		case msg := <-sub.Messages():
+			var e event
+			if err := msg.Decode(&e); err != nil {
+				t.Fatal(err)
+			}
+			if e.Id != id || msg.Channel != "events" || msg.Route != route {
+				t.Fatalf("unexpected message %+v %+v", msg, e)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for event %d", id)
+		}
+	}
+
+	expectEvent(1)
+
+	ConnectByConf(route, &ConnConf{Servers: []string{newServer(`{"id":2}`)}})
+	expectEvent(2)
+
+	if err := sub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-sub.Messages(); ok {
+		t.Error("expect messages channel closed")
+	}
+	if err := sub.Subscribe(NewKey(route, "events")); err != ErrSubscriberClosed {
+		t.Errorf("expect closed error, got %v", err)
+	}
+}
+
+func TestSubscriberCloseWhilePoolExhausted(t *testing.T) {
+	addr := newFakeRedis(t, func(args []string) any {
+		return "PONG"
+	})
+
+	c := NewClient()
+	if err := c.ConnectByConf("main", &ConnConf{Servers: []string{addr}, MaxConnPoolSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterKeyRoute("sub.exhausted", "main")
+	pool, err := c.GetConnPool("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := pool.Get()
+	defer held.Close()
+
+	sub := NewSubscriber()
+	if err = sub.Subscribe(c.NewKey("sub.exhausted", "events")); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		_ = sub.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expect Close to cancel the pending pool wait")
+	}
+}
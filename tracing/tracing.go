@@ -0,0 +1,188 @@
+// Package tracing 为routeredis的命令和管道创建opentelemetry span, span的父节点取自调用方传入的ctx
+package tracing
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/995933447/routeredis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ScopeName = "github.com/995933447/routeredis/tracing"
+
+const (
+	AttrRoute       = attribute.Key("routeredis.route")
+	AttrConn        = attribute.Key("routeredis.conn")
+	AttrWay         = attribute.Key("routeredis.way")
+	AttrKey         = attribute.Key("db.redis.key")
+	AttrExpireCmd   = attribute.Key("routeredis.expire.cmd")
+	AttrExpireTTL   = attribute.Key("routeredis.expire.ttl")
+	AttrExpireAsync = attribute.Key("routeredis.expire.async")
+	AttrBatchSize   = attribute.Key("db.operation.batch.size")
+	AttrBatchCmds   = attribute.Key("routeredis.pipeline.cmds")
+	AttrBatchRoutes = attribute.Key("routeredis.pipeline.routes")
+)
+
+var (
+	attrDBSystem      = attribute.Key("db.system.name").String("redis")
+	attrOperationName = attribute.Key("db.operation.name")
+	attrServerAddress = attribute.Key("server.address")
+)
+
+type Opts struct {
+	TracerProvider trace.TracerProvider // 为空时使用otel.GetTracerProvider()
+	// KeyAttr 返回记录在span上的key, 为空时记录完整的RedisKey, 返回空字符串时不记录, 可以使用RedactKey隐藏业务部分
+	KeyAttr func(key *routeredis.Key) string
+}
+
+// RedactKey 只保留key的前缀部分, 例如"test:user:*"
+func RedactKey(key *routeredis.Key) string {
+	return strings.TrimSuffix(key.RedisKey(), key.Key) + "*"
+}
+
+type Tracer struct {
+	tracer  trace.Tracer
+	keyAttr func(key *routeredis.Key) string
+}
+
+func New(opts *Opts) *Tracer {
+	if opts == nil {
+		opts = &Opts{}
+	}
+
+	provider := opts.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	keyAttr := opts.KeyAttr
+	if keyAttr == nil {
+		keyAttr = func(key *routeredis.Key) string {
+			return key.RedisKey()
+		}
+	}
+
+	return &Tracer{
+		tracer:  provider.Tracer(ScopeName),
+		keyAttr: keyAttr,
+	}
+}
+
+// Instrument 创建Tracer并把命令和管道中间件加入client, client为nil时使用routeredis.DefaultClient
+func Instrument(client *routeredis.Client, opts *Opts) *Tracer {
+	if client == nil {
+		client = routeredis.DefaultClient()
+	}
+	t := New(opts)
+	client.Use(t.Middleware())
+	client.UsePipeline(t.PipelineMiddleware())
+	return t
+}
+
+// Middleware 为DoCmdWithTTL和SendCmdWithTTL创建span, 带有ttl的命令在span上记录随后的EXPIRE
+func (t *Tracer) Middleware() routeredis.CmdMiddleware {
+	return func(next routeredis.CmdHandler) routeredis.CmdHandler {
+		return func(ctx context.Context, cmd *routeredis.Cmd) (any, error) {
+			attrs := []attribute.KeyValue{
+				attrDBSystem,
+				attrOperationName.String(cmd.Name),
+				AttrRoute.String(cmd.Key.Route),
+				AttrWay.String(cmd.Way),
+			}
+			if connName, err := routeredis.KeyConnName(cmd.Key); err == nil {
+				attrs = append(attrs, AttrConn.String(connName))
+			}
+			if node := clusterNode(cmd.Key); node != "" {
+				attrs = append(attrs, attrServerAddress.String(node))
+			}
+			if key := t.keyAttr(cmd.Key); key != "" {
+				attrs = append(attrs, AttrKey.String(key))
+			}
+
+			ctx, span := t.tracer.Start(ctx, "redis "+cmd.Name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			res, err := next(ctx, cmd)
+			if err != nil {
+				endWithErr(span, err)
+				return res, err
+			}
+
+			if cmd.TTL != nil && cmd.TTL.TTL > 0 {
+				expireCmd := "EXPIRE"
+				if cmd.TTL.IsMillisecond {
+					expireCmd = "PEXPIRE"
+				}
+				span.AddEvent(expireCmd, trace.WithAttributes(
+					AttrExpireCmd.String(expireCmd),
+					AttrExpireTTL.Int64(cmd.TTL.TTL),
+					AttrExpireAsync.Bool(cmd.TTL.IsAsyncTTL),
+				))
+			}
+
+			return res, nil
+		}
+	}
+}
+
+// PipelineMiddleware 管道中发往同一连接(集群模式下同一节点)的每批命令一个span
+func (t *Tracer) PipelineMiddleware() routeredis.PipelineMiddleware {
+	return func(next routeredis.PipelineHandler) routeredis.PipelineHandler {
+		return func(ctx context.Context, batch *routeredis.PipelineBatch) {
+			var cmds, routes []string
+			for _, cmd := range batch.Cmds {
+				cmds = append(cmds, cmd.Cmd)
+				if !slices.Contains(routes, cmd.Key.Route) {
+					routes = append(routes, cmd.Key.Route)
+				}
+			}
+
+			attrs := []attribute.KeyValue{
+				attrDBSystem,
+				attrOperationName.String("PIPELINE"),
+				AttrConn.String(batch.ConnName),
+				AttrBatchSize.Int(len(batch.Cmds)),
+				AttrBatchCmds.StringSlice(cmds),
+				AttrBatchRoutes.StringSlice(routes),
+			}
+			if batch.Node != "" {
+				attrs = append(attrs, attrServerAddress.String(batch.Node))
+			}
+
+			ctx, span := t.tracer.Start(ctx, "redis PIPELINE", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			next(ctx, batch)
+
+			for _, cmd := range batch.Cmds {
+				if cmd.Err != nil {
+					endWithErr(span, cmd.Err)
+					return
+				}
+			}
+		}
+	}
+}
+
+func clusterNode(key *routeredis.Key) string {
+	pool, err := routeredis.KeyConnPool(key)
+	if err != nil {
+		return ""
+	}
+	cluster, ok := pool.(*routeredis.RedisCluster)
+	if !ok {
+		return ""
+	}
+	addr, _ := cluster.KeyAddr(key.RedisKey())
+	return addr
+}
+
+func endWithErr(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
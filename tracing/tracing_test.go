@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/995933447/routeredis"
+	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// stubConn 命令原样返回key, Send的命令在Receive时依次应答
+type stubConn struct {
+	pending []string
+}
+
+func (c *stubConn) Close() error { return nil }
+func (c *stubConn) Err() error   { return nil }
+func (c *stubConn) Do(cmd string, args ...any) (any, error) {
+	if cmd == "" {
+		return nil, c.Flush()
+	}
+	return args[0], nil
+}
+func (c *stubConn) DoContext(_ context.Context, cmd string, args ...any) (any, error) {
+	return c.Do(cmd, args...)
+}
+func (c *stubConn) Send(_ string, args ...any) error {
+	c.pending = append(c.pending, args[0].(string))
+	return nil
+}
+func (c *stubConn) Flush() error { return nil }
+func (c *stubConn) Receive() (any, error) {
+	reply := c.pending[0]
+	c.pending = c.pending[1:]
+	return reply, nil
+}
+func (c *stubConn) ReceiveContext(context.Context) (any, error) { return c.Receive() }
+
+func TestTracing(t *testing.T) {
+	client := routeredis.NewClient()
+	client.Connect("main", &redis.Pool{Dial: func() (redis.Conn, error) { return &stubConn{}, nil }})
+	client.SetConnKeyPrefix("main", "test:")
+	client.RegisterKeyRoute("user", "main")
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	Instrument(client, &Opts{TracerProvider: provider, KeyAttr: RedactKey})
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	if _, err := routeredis.DoCmdWithTTLContext(ctx, routeredis.NewSyncSecTTL(10), "SET", client.NewKey("user", "1"), "v"); err != nil {
+		t.Fatal(err)
+	}
+
+	p := routeredis.NewPipeline()
+	p.Do(nil, "GET", client.NewKey("user", "1"))
+	p.Do(nil, "GET", client.NewKey("user", "2"))
+	if _, err := p.ExecContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expect 3 spans, got %d", len(spans))
+	}
+
+	cmdSpan, pipelineSpan := spans[0], spans[1]
+	if cmdSpan.Name() != "redis SET" || cmdSpan.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("unexpected cmd span %s parent %v", cmdSpan.Name(), cmdSpan.Parent())
+	}
+	attrs := attribute.NewSet(cmdSpan.Attributes()...)
+	if v, _ := attrs.Value(AttrKey); v.AsString() != "test:*" {
+		t.Fatalf("expect redacted key, got %q", v.AsString())
+	}
+	if v, _ := attrs.Value(AttrConn); v.AsString() != "main" {
+		t.Fatalf("expect conn attr, got %q", v.AsString())
+	}
+	if events := cmdSpan.Events(); len(events) != 1 || events[0].Name != "EXPIRE" {
+		t.Fatalf("expect EXPIRE event, got %v", events)
+	}
+
+	if pipelineSpan.Name() != "redis PIPELINE" || pipelineSpan.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("unexpected pipeline span %s", pipelineSpan.Name())
+	}
+	pipelineAttrs := attribute.NewSet(pipelineSpan.Attributes()...)
+	if v, _ := pipelineAttrs.Value(AttrBatchSize); v.AsInt64() != 2 {
+		t.Fatalf("expect batch size 2, got %d", v.AsInt64())
+	}
+}
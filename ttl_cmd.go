@@ -1,11 +1,21 @@
 package routeredis
 
+import "context"
+
 func AsyncExpire(key *Key, ttl int64) error {
-	return SendCmdWithTTL(nil, "EXPIRE", key, ttl)
+	return AsyncExpireCtx(context.Background(), key, ttl)
+}
+
+func AsyncExpireCtx(ctx context.Context, key *Key, ttl int64) error {
+	return SendCmdWithTTLContext(ctx, nil, "EXPIRE", key, ttl)
 }
 
 func Expire(key *Key, ttl int64) error {
-	_, err := DoCmdWithTTL(nil, "EXPIRE", key, ttl)
+	return ExpireCtx(context.Background(), key, ttl)
+}
+
+func ExpireCtx(ctx context.Context, key *Key, ttl int64) error {
+	_, err := DoCmdWithTTLContext(ctx, nil, "EXPIRE", key, ttl)
 	if err != nil {
 		return err
 	}
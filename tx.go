@@ -0,0 +1,243 @@
+package routeredis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+var (
+	ErrTxWatchConflict    = errors.New("redis tx watched keys modified")
+	ErrTxKeyRouteMismatch = errors.New("redis tx keys must share the tx route")
+	ErrTxCrossSlot        = errors.New("redis tx keys must share one cluster slot")
+	ErrTxMaxRetries       = errors.New("redis tx max retries exceeded")
+)
+
+const DefaultTxMaxRetries = 3
+
+type TxFunc func(tx *Tx) error
+
+type txCmd struct {
+	ttl  *TTL
+	cmd  string
+	key  *Key
+	args []any
+}
+
+// Tx 绑定在一个路由上的MULTI/EXEC事务, TxFunc中通过Watch监视key, 通过Do读取数据,
+// 通过Queue排队写命令, Exec时被监视的key发生变化会重新执行TxFunc. Tx不能并发执行
+type Tx struct {
+	client     *Client
+	route      string
+	maxRetries int
+	ctx        context.Context
+	conn       redis.Conn
+	isCluster  bool
+	slot       int
+	queued     []*txCmd
+}
+
+func NewTx(route string) *Tx {
+	return defaultClient.NewTx(route)
+}
+
+// NewTx 创建绑定在c的路由上的事务, 事务中的key也必须属于c
+func (c *Client) NewTx(route string) *Tx {
+	return &Tx{
+		client:     c,
+		route:      route,
+		maxRetries: DefaultTxMaxRetries,
+	}
+}
+
+func (t *Tx) SetMaxRetries(maxRetries int) *Tx {
+	t.maxRetries = maxRetries
+	return t
+}
+
+func (t *Tx) Route() string {
+	return t.route
+}
+
+func (t *Tx) Exec(fn TxFunc) ([]any, error) {
+	return t.ExecContext(context.Background(), fn)
+}
+
+// ExecContext 执行事务, 返回EXEC中每条排队命令的应答, 发生WATCH冲突时最多重试maxRetries次
+func (t *Tx) ExecContext(ctx context.Context, fn TxFunc) ([]any, error) {
+	for i := 0; i <= t.maxRetries; i++ {
+		replies, err := t.exec(ctx, fn)
+		if !errors.Is(err, ErrTxWatchConflict) {
+			return replies, err
+		}
+	}
+	return nil, ErrTxMaxRetries
+}
+
+func (t *Tx) exec(ctx context.Context, fn TxFunc) (replies []any, err error) {
+	pool, err := t.client.RouteConnPool(t.route)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn redis.Conn
+	t.isCluster = false
+	switch p := pool.(type) {
+	case *RedisCluster:
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		// 事务需要在同一条连接上Send/Receive, 不能使用RetryConn
+		conn = p.Cluster.Get()
+		t.isCluster = true
+	case *SentinelPool:
+		// 开启ReadFromReplicas时Do中的只读命令会发往从库, 读到的数据不受WATCH保护, 事务固定使用主库连接
+		if conn, err = p.master.GetContext(ctx); err != nil {
+			return nil, err
+		}
+	default:
+		if conn, err = getPoolConnContext(ctx, pool); err != nil {
+			return nil, err
+		}
+	}
+	defer conn.Close()
+
+	if err = conn.Err(); err != nil {
+		return nil, err
+	}
+
+	t.ctx, t.conn, t.slot, t.queued = ctx, conn, -1, nil
+	defer func() {
+		t.ctx, t.conn, t.queued = nil, nil, nil
+	}()
+
+	if onCmdDone := t.client.cmdDoneHook(); onCmdDone != nil {
+		start := time.Now()
+		defer func() {
+			for _, cmd := range t.queued {
+				onCmdDone("Tx", cmd.ttl, time.Since(start), cmd.cmd, cmd.key, err, cmd.args...)
+			}
+		}()
+	}
+
+	if err = fn(t); err != nil {
+		_, _ = doContext(ctx, conn, "UNWATCH")
+		return nil, err
+	}
+
+	if len(t.queued) == 0 {
+		_, _ = doContext(ctx, conn, "UNWATCH")
+		return nil, nil
+	}
+
+	if err = conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	for _, cmd := range t.queued {
+		if err = conn.Send(cmd.cmd, append([]any{cmd.key.RedisKey()}, cmd.args...)...); err != nil {
+			return nil, err
+		}
+		if cmd.ttl != nil && cmd.ttl.TTL > 0 {
+			if err = conn.Send(cmd.ttl.expireCmd(), cmd.key.RedisKey(), cmd.ttl.TTL); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	res, err := doContext(ctx, conn, "EXEC")
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, ErrTxWatchConflict
+	}
+
+	execReplies, err := redis.Values(res, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 去掉EXPIRE的应答, 只保留排队命令自身的应答
+	replies = make([]any, 0, len(t.queued))
+	idx := 0
+	for _, cmd := range t.queued {
+		if idx >= len(execReplies) {
+			break
+		}
+		replies = append(replies, execReplies[idx])
+		idx++
+		if cmd.ttl != nil && cmd.ttl.TTL > 0 {
+			idx++
+		}
+	}
+
+	return replies, nil
+}
+
+// Watch 监视key, 需要在Do和Queue之前调用
+func (t *Tx) Watch(keys ...*Key) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(keys))
+	for _, key := range keys {
+		if err := t.checkKey(key); err != nil {
+			return err
+		}
+		args = append(args, key.RedisKey())
+	}
+
+	_, err := doContext(t.ctx, t.conn, "WATCH", args...)
+	return err
+}
+
+// Do 在事务连接上立即执行命令, 用于在WATCH之后读取数据
+func (t *Tx) Do(cmd string, key *Key, args ...any) (any, error) {
+	if err := t.checkKey(key); err != nil {
+		return nil, err
+	}
+	return doContext(t.ctx, t.conn, cmd, append([]any{key.RedisKey()}, args...)...)
+}
+
+// Queue 将命令排入事务, 在EXEC时原子执行
+func (t *Tx) Queue(ttl *TTL, cmd string, key *Key, args ...any) error {
+	if err := t.checkKey(key); err != nil {
+		return err
+	}
+	t.queued = append(t.queued, &txCmd{
+		ttl:  ttl,
+		cmd:  cmd,
+		key:  key,
+		args: args,
+	})
+	return nil
+}
+
+func (t *Tx) checkKey(key *Key) error {
+	if key.Route != t.route || key.Client() != t.client {
+		return ErrTxKeyRouteMismatch
+	}
+
+	if !t.isCluster {
+		return nil
+	}
+
+	slot := redisc.Slot(key.RedisKey())
+	if t.slot == -1 {
+		if err := redisc.BindConn(t.conn, key.RedisKey()); err != nil {
+			return err
+		}
+		t.slot = slot
+		return nil
+	}
+
+	if slot != t.slot {
+		return ErrTxCrossSlot
+	}
+
+	return nil
+}
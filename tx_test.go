@@ -0,0 +1,59 @@
+package routeredis
+
+import (
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestTxRetryOnWatchConflict(t *testing.T) {
+	var execCount int
+	addr := newFakeRedis(t, func(args []string) any {
+		switch args[0] {
+		case "WATCH", "MULTI", "UNWATCH":
+			return "OK"
+		case "GET":
+			return "1"
+		case "EXEC":
+			execCount++
+			if execCount == 1 {
+				return nil
+			}
+			return []any{"OK", 1}
+		}
+		return "QUEUED"
+	})
+
+	const route = "tx_retry"
+	ConnectByConf(route, &ConnConf{Servers: []string{addr}})
+	RegisterKeyRoute(route, route)
+
+	key := NewKey(route, "counter")
+	var runs int
+	replies, err := NewTx(route).Exec(func(tx *Tx) error {
+		runs++
+		if err := tx.Watch(key); err != nil {
+			return err
+		}
+		n, err := redis.Int(tx.Do("GET", key))
+		if err != nil {
+			return err
+		}
+		return tx.Queue(NewSyncSecTTL(60), "SET", key, n+1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if runs != 2 {
+		t.Errorf("expect fn to rerun after conflict, ran %d times", runs)
+	}
+	if res, _ := redis.String(replies[0], nil); len(replies) != 1 || res != "OK" {
+		t.Errorf("expect only SET reply, got %v", replies)
+	}
+
+	err = NewTx(route).Queue(nil, "SET", NewKey("other", "k"))
+	if err != ErrTxKeyRouteMismatch {
+		t.Errorf("expect route mismatch, got %v", err)
+	}
+}
@@ -1,21 +1,29 @@
 package routeredis
 
 import (
+	"context"
 	"errors"
 	"strconv"
 
 	"github.com/gomodule/redigo/redis"
-	jsoniter "github.com/json-iterator/go"
 )
 
 func Zcard(key *Key) (int64, error) {
-	return redis.Int64(DoCmdWithTTL(nil, "ZCARD", key))
+	return ZcardCtx(context.Background(), key)
+}
+
+func ZcardCtx(ctx context.Context, key *Key) (int64, error) {
+	return redis.Int64(DoCmdWithTTLContext(ctx, nil, "ZCARD", key))
 }
 
 func ZscanWithScore(key *Key, cursor, count int64) (int64, map[string]int64, error) {
+	return ZscanWithScoreCtx(context.Background(), key, cursor, count)
+}
+
+func ZscanWithScoreCtx(ctx context.Context, key *Key, cursor, count int64) (int64, map[string]int64, error) {
 	mapKeyToScore := map[string]int64{}
 
-	res, err := redis.Values(DoCmdWithTTL(nil, "ZSCAN", key, cursor, "COUNT", count))
+	res, err := redis.Values(DoCmdWithTTLContext(ctx, nil, "ZSCAN", key, cursor, "COUNT", count))
 	if err != nil {
 		return 0, nil, err
 	}
@@ -54,7 +62,11 @@ func ZscanWithScore(key *Key, cursor, count int64) (int64, map[string]int64, err
 }
 
 func ZscanWithoutScore(key *Key, cursor, count int64) (int64, []string, error) {
-	res, err := redis.Values(DoCmdWithTTL(nil, "ZSCAN", key, cursor, "COUNT", count))
+	return ZscanWithoutScoreCtx(context.Background(), key, cursor, count)
+}
+
+func ZscanWithoutScoreCtx(ctx context.Context, key *Key, cursor, count int64) (int64, []string, error) {
+	res, err := redis.Values(DoCmdWithTTLContext(ctx, nil, "ZSCAN", key, cursor, "COUNT", count))
 	if err != nil {
 		return 0, nil, err
 	}
@@ -82,16 +94,16 @@ func ZscanWithoutScore(key *Key, cursor, count int64) (int64, []string, error) {
 }
 
 func Zadd(key *Key, score int64, data any, ttl int64) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return ZaddCtx(context.Background(), key, score, data, ttl)
+}
+
+func ZaddCtx(ctx context.Context, key *Key, score int64, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	_, err := DoCmdWithTTL(NewAsyncSecTTL(ttl), "ZADD", key, score, str)
+	_, err = DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "ZADD", key, score, str)
 	if err != nil {
 		return err
 	}
@@ -100,16 +112,16 @@ func Zadd(key *Key, score int64, data any, ttl int64) error {
 }
 
 func AsyncZadd(key *Key, score int64, data any, ttl int64) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return AsyncZaddCtx(context.Background(), key, score, data, ttl)
+}
+
+func AsyncZaddCtx(ctx context.Context, key *Key, score int64, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
 
-	if _, err := DoCmdWithTTL(NewAsyncSecTTL(ttl), "ZADD", key, score, str); err != nil {
+	if _, err := DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "ZADD", key, score, str); err != nil {
 		return err
 	}
 
@@ -117,7 +129,11 @@ func AsyncZadd(key *Key, score int64, data any, ttl int64) error {
 }
 
 func ZaddMany(ttl int64, key *Key, data ...any) error {
-	if _, err := DoCmdWithTTL(NewAsyncSecTTL(ttl), "ZADD", key, data...); err != nil {
+	return ZaddManyCtx(context.Background(), ttl, key, data...)
+}
+
+func ZaddManyCtx(ctx context.Context, ttl int64, key *Key, data ...any) error {
+	if _, err := DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "ZADD", key, data...); err != nil {
 		return err
 	}
 
@@ -125,11 +141,29 @@ func ZaddMany(ttl int64, key *Key, data ...any) error {
 }
 
 func Zscore(key *Key, data any) (int64, error) {
-	return redis.Int64(DoCmdWithTTL(nil, "ZSCORE", key, data))
+	return ZscoreCtx(context.Background(), key, data)
+}
+
+func ZscoreCtx(ctx context.Context, key *Key, data any) (int64, error) {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return 0, err
+	}
+
+	return redis.Int64(DoCmdWithTTLContext(ctx, nil, "ZSCORE", key, str))
 }
 
 func Zincrby(key *Key, inc int64, data any, ttl int64) error {
-	_, err := DoCmdWithTTL(NewAsyncSecTTL(ttl), "ZINCRBY", key, inc, data)
+	return ZincrbyCtx(context.Background(), key, inc, data, ttl)
+}
+
+func ZincrbyCtx(ctx context.Context, key *Key, inc int64, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = DoCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "ZINCRBY", key, inc, str)
 	if err != nil {
 		return err
 	}
@@ -138,24 +172,45 @@ func Zincrby(key *Key, inc int64, data any, ttl int64) error {
 }
 
 func AsyncZincrby(key *Key, inc int64, data any, ttl int64) error {
-	return SendCmdWithTTL(NewAsyncSecTTL(ttl), "ZINCRBY", key, inc, data)
+	return AsyncZincrbyCtx(context.Background(), key, inc, data, ttl)
+}
+
+func AsyncZincrbyCtx(ctx context.Context, key *Key, inc int64, data any, ttl int64) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
+	}
+
+	return SendCmdWithTTLContext(ctx, NewAsyncSecTTL(ttl), "ZINCRBY", key, inc, str)
 }
 
 func Zcount(key *Key, start any, end any) (int64, error) {
-	return redis.Int64(DoCmdWithTTL(nil, "ZCOUNT", key, start, end))
+	return ZcountCtx(context.Background(), key, start, end)
+}
+
+func ZcountCtx(ctx context.Context, key *Key, start any, end any) (int64, error) {
+	return redis.Int64(DoCmdWithTTLContext(ctx, nil, "ZCOUNT", key, start, end))
 }
 
 func Zrevrangebyscore(key *Key, start, end any, withScore bool) ([]string, error) {
+	return ZrevrangebyscoreCtx(context.Background(), key, start, end, withScore)
+}
+
+func ZrevrangebyscoreCtx(ctx context.Context, key *Key, start, end any, withScore bool) ([]string, error) {
 	var args []any
 	args = append(args, end)
 	args = append(args, start)
 	if withScore {
 		args = append(args, "WITHSCORES")
 	}
-	return redis.Strings(DoCmdWithTTL(nil, "ZREVRANGEBYSCORE", key, args...))
+	return redis.Strings(DoCmdWithTTLContext(ctx, nil, "ZREVRANGEBYSCORE", key, args...))
 }
 
 func ZrevrangebyscoreInt64s(key *Key, start, end any, withScore bool) ([]int64, error) {
+	return ZrevrangebyscoreInt64sCtx(context.Background(), key, start, end, withScore)
+}
+
+func ZrevrangebyscoreInt64sCtx(ctx context.Context, key *Key, start, end any, withScore bool) ([]int64, error) {
 	var args []any
 	args = append(args, key)
 	args = append(args, end)
@@ -163,31 +218,43 @@ func ZrevrangebyscoreInt64s(key *Key, start, end any, withScore bool) ([]int64,
 	if withScore {
 		args = append(args, "WITHSCORES")
 	}
-	return redis.Int64s(DoCmdWithTTL(nil, "ZREVRANGEBYSCORE", key, args...))
+	return redis.Int64s(DoCmdWithTTLContext(ctx, nil, "ZREVRANGEBYSCORE", key, args...))
 }
 
 func Zrangerbyscore(key *Key, start, end any, withScore bool) ([]string, error) {
+	return ZrangerbyscoreCtx(context.Background(), key, start, end, withScore)
+}
+
+func ZrangerbyscoreCtx(ctx context.Context, key *Key, start, end any, withScore bool) ([]string, error) {
 	var args []any
 	args = append(args, start)
 	args = append(args, end)
 	if withScore {
 		args = append(args, "WITHSCORES")
 	}
-	return redis.Strings(DoCmdWithTTL(nil, "ZRANGEBYSCORE", key, args...))
+	return redis.Strings(DoCmdWithTTLContext(ctx, nil, "ZRANGEBYSCORE", key, args...))
 }
 
 func ZrangerbyscoreInt64s(key *Key, start any, end any, withScore bool) ([]int64, error) {
+	return ZrangerbyscoreInt64sCtx(context.Background(), key, start, end, withScore)
+}
+
+func ZrangerbyscoreInt64sCtx(ctx context.Context, key *Key, start any, end any, withScore bool) ([]int64, error) {
 	var args []any
 	args = append(args, start)
 	args = append(args, end)
 	if withScore {
 		args = append(args, "WITHSCORES")
 	}
-	return redis.Int64s(DoCmdWithTTL(nil, "ZRANGEBYSCORE", key, args...))
+	return redis.Int64s(DoCmdWithTTLContext(ctx, nil, "ZRANGEBYSCORE", key, args...))
 }
 
 func Zremrangebyrank(key *Key, start, end any) error {
-	_, err := DoCmdWithTTL(nil, "ZREMRANGEBYRANK", key, start, end)
+	return ZremrangebyrankCtx(context.Background(), key, start, end)
+}
+
+func ZremrangebyrankCtx(ctx context.Context, key *Key, start, end any) error {
+	_, err := DoCmdWithTTLContext(ctx, nil, "ZREMRANGEBYRANK", key, start, end)
 	if err != nil {
 		return err
 	}
@@ -196,10 +263,18 @@ func Zremrangebyrank(key *Key, start, end any) error {
 }
 
 func AsyncZremrangebyscore(key *Key, start, end any) error {
-	return SendCmdWithTTL(nil, "ZREMRANGEBYSCORE", key, start, end)
+	return AsyncZremrangebyscoreCtx(context.Background(), key, start, end)
+}
+
+func AsyncZremrangebyscoreCtx(ctx context.Context, key *Key, start, end any) error {
+	return SendCmdWithTTLContext(ctx, nil, "ZREMRANGEBYSCORE", key, start, end)
 }
 
 func Zrange(key *Key, start, end any, withScore bool) ([]string, error) {
+	return ZrangeCtx(context.Background(), key, start, end, withScore)
+}
+
+func ZrangeCtx(ctx context.Context, key *Key, start, end any, withScore bool) ([]string, error) {
 	var args []any
 	args = append(args, start)
 	args = append(args, end)
@@ -207,10 +282,14 @@ func Zrange(key *Key, start, end any, withScore bool) ([]string, error) {
 		args = append(args, "WITHSCORES")
 	}
 
-	return redis.Strings(DoCmdWithTTL(nil, "ZRANGE", key, args...))
+	return redis.Strings(DoCmdWithTTLContext(ctx, nil, "ZRANGE", key, args...))
 }
 
 func ZrangeInt64s(key *Key, start, end any, withScore bool) ([]int64, error) {
+	return ZrangeInt64sCtx(context.Background(), key, start, end, withScore)
+}
+
+func ZrangeInt64sCtx(ctx context.Context, key *Key, start, end any, withScore bool) ([]int64, error) {
 	var args []any
 	args = append(args, start)
 	args = append(args, end)
@@ -218,10 +297,14 @@ func ZrangeInt64s(key *Key, start, end any, withScore bool) ([]int64, error) {
 		args = append(args, "WITHSCORES")
 	}
 
-	return redis.Int64s(DoCmdWithTTL(nil, "ZRANGE", key, args...))
+	return redis.Int64s(DoCmdWithTTLContext(ctx, nil, "ZRANGE", key, args...))
 }
 
 func Zrevrange(key *Key, start, end any, withScore bool) ([]string, error) {
+	return ZrevrangeCtx(context.Background(), key, start, end, withScore)
+}
+
+func ZrevrangeCtx(ctx context.Context, key *Key, start, end any, withScore bool) ([]string, error) {
 	var args []any
 	args = append(args, start)
 	args = append(args, end)
@@ -229,10 +312,14 @@ func Zrevrange(key *Key, start, end any, withScore bool) ([]string, error) {
 		args = append(args, "WITHSCORES")
 	}
 
-	return redis.Strings(DoCmdWithTTL(nil, "ZREVRANGE", key, args...))
+	return redis.Strings(DoCmdWithTTLContext(ctx, nil, "ZREVRANGE", key, args...))
 }
 
 func ZrevrangeInt64s(key *Key, start, end any, withScore bool) ([]int64, error) {
+	return ZrevrangeInt64sCtx(context.Background(), key, start, end, withScore)
+}
+
+func ZrevrangeInt64sCtx(ctx context.Context, key *Key, start, end any, withScore bool) ([]int64, error) {
 	var args []any
 	args = append(args, start)
 	args = append(args, end)
@@ -240,36 +327,40 @@ func ZrevrangeInt64s(key *Key, start, end any, withScore bool) ([]int64, error)
 		args = append(args, "WITHSCORES")
 	}
 
-	return redis.Int64s(DoCmdWithTTL(nil, "ZREVRANGE", key, args...))
+	return redis.Int64s(DoCmdWithTTLContext(ctx, nil, "ZREVRANGE", key, args...))
 }
 
 func Zrevrank(key *Key, data any) (int64, error) {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return 0, err
-		}
+	return ZrevrankCtx(context.Background(), key, data)
+}
+
+func ZrevrankCtx(ctx context.Context, key *Key, data any) (int64, error) {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return 0, err
 	}
 
-	return redis.Int64(DoCmdWithTTL(nil, "ZREVRANK", key, str))
+	return redis.Int64(DoCmdWithTTLContext(ctx, nil, "ZREVRANK", key, str))
 }
 
 func AsyncZrem(key *Key, data any) error {
-	str, ok := data.(string)
-	if !ok {
-		var err error
-		str, err = jsoniter.MarshalToString(data)
-		if err != nil {
-			return err
-		}
+	return AsyncZremCtx(context.Background(), key, data)
+}
+
+func AsyncZremCtx(ctx context.Context, key *Key, data any) error {
+	str, err := encodeValue(key, data)
+	if err != nil {
+		return err
 	}
-	return SendCmdWithTTL(nil, "ZREM", key, str)
+	return SendCmdWithTTLContext(ctx, nil, "ZREM", key, str)
 }
 
 func Zexists(key *Key, member string) (bool, error) {
-	_, err := redis.Int64(DoCmdWithTTL(nil, "ZSCORE", key, member))
+	return ZexistsCtx(context.Background(), key, member)
+}
+
+func ZexistsCtx(ctx context.Context, key *Key, member string) (bool, error) {
+	_, err := redis.Int64(DoCmdWithTTLContext(ctx, nil, "ZSCORE", key, member))
 	if err != nil {
 		if !errors.Is(err, redis.ErrNil) {
 			return false, err
@@ -281,7 +372,11 @@ func Zexists(key *Key, member string) (bool, error) {
 }
 
 func Zunionstore(key1, key2 *Key) (int64, error) {
-	count, err := redis.Int64(DoCmdWithTTL(nil, "ZUNIONSTORE", key2, 1, key1.Key))
+	return ZunionstoreCtx(context.Background(), key1, key2)
+}
+
+func ZunionstoreCtx(ctx context.Context, key1, key2 *Key) (int64, error) {
+	count, err := redis.Int64(DoCmdWithTTLContext(ctx, nil, "ZUNIONSTORE", key2, 1, key1.RedisKey()))
 	if err != nil {
 		if !errors.Is(err, redis.ErrNil) {
 			return 0, err